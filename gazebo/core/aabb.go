@@ -0,0 +1,133 @@
+package core
+
+import "math"
+
+// axis-aligned bounding box, matches std430 struct { vec2 min; vec2 max; }
+type AABB2 struct {
+	Min Vec2 // lower corner
+	Max Vec2 // upper corner
+}
+
+// axis-aligned bounding box, matches std430 struct { vec3 min; vec3 max; },
+// where both vec3 are aligned to 16 bytes
+type AABB3 struct {
+	Min Vec3 // lower corner
+	_   float32
+	Max Vec3 // upper corner
+	_   float32
+}
+
+// box containing nothing, extending it with a point gives box of that point
+func EmptyAABB2() AABB2 {
+	inf := float32(math.Inf(1))
+	return AABB2{Min: Vec2{X: inf, Y: inf}, Max: Vec2{X: -inf, Y: -inf}}
+}
+
+// box containing nothing, extending it with a point gives box of that point
+func EmptyAABB3() AABB3 {
+	inf := float32(math.Inf(1))
+	return AABB3{Min: Vec3{X: inf, Y: inf, Z: inf}, Max: Vec3{X: -inf, Y: -inf, Z: -inf}}
+}
+
+// smallest box containing all points, empty box if there are none
+func AABB2Of(points ...Vec2) AABB2 {
+	b := EmptyAABB2()
+	for _, p := range points {
+		b = b.Extend(p)
+	}
+	return b
+}
+
+// smallest box containing all points, empty box if there are none
+func AABB3Of(points ...Vec3) AABB3 {
+	b := EmptyAABB3()
+	for _, p := range points {
+		b = b.Extend(p)
+	}
+	return b
+}
+
+func (b AABB2) Empty() bool {
+	return b.Min.X > b.Max.X || b.Min.Y > b.Max.Y
+}
+
+func (b AABB2) Size() Vec2 {
+	return b.Max.Sub(b.Min)
+}
+
+func (b AABB2) Center() Vec2 {
+	return b.Min.Add(b.Max).Scale(0.5)
+}
+
+// true if point is inside box or on its border
+func (b AABB2) Contains(p Vec2) bool {
+	return p.X >= b.Min.X && p.X <= b.Max.X && p.Y >= b.Min.Y && p.Y <= b.Max.Y
+}
+
+// true if boxes have at least one common point
+func (b AABB2) Intersects(o AABB2) bool {
+	return b.Min.X <= o.Max.X && o.Min.X <= b.Max.X && b.Min.Y <= o.Max.Y && o.Min.Y <= b.Max.Y
+}
+
+// smallest box containing b and point
+func (b AABB2) Extend(p Vec2) AABB2 {
+	return AABB2{Min: b.Min.Min(p), Max: b.Max.Max(p)}
+}
+
+// smallest box containing both boxes
+func (b AABB2) Union(o AABB2) AABB2 {
+	return AABB2{Min: b.Min.Min(o.Min), Max: b.Max.Max(o.Max)}
+}
+
+func (b AABB3) Empty() bool {
+	return b.Min.X > b.Max.X || b.Min.Y > b.Max.Y || b.Min.Z > b.Max.Z
+}
+
+func (b AABB3) Size() Vec3 {
+	return b.Max.Sub(b.Min)
+}
+
+func (b AABB3) Center() Vec3 {
+	return b.Min.Add(b.Max).Scale(0.5)
+}
+
+// true if point is inside box or on its border
+func (b AABB3) Contains(p Vec3) bool {
+	return p.X >= b.Min.X && p.X <= b.Max.X && p.Y >= b.Min.Y && p.Y <= b.Max.Y && p.Z >= b.Min.Z && p.Z <= b.Max.Z
+}
+
+// true if boxes have at least one common point
+func (b AABB3) Intersects(o AABB3) bool {
+	return b.Min.X <= o.Max.X && o.Min.X <= b.Max.X &&
+		b.Min.Y <= o.Max.Y && o.Min.Y <= b.Max.Y &&
+		b.Min.Z <= o.Max.Z && o.Min.Z <= b.Max.Z
+}
+
+// smallest box containing b and point
+func (b AABB3) Extend(p Vec3) AABB3 {
+	return AABB3{Min: b.Min.Min(p), Max: b.Max.Max(p)}
+}
+
+// smallest box containing both boxes
+func (b AABB3) Union(o AABB3) AABB3 {
+	return AABB3{Min: b.Min.Min(o.Min), Max: b.Max.Max(o.Max)}
+}
+
+// box containing all corners of b transformed by m
+func (b AABB3) Transform(m Mat4) AABB3 {
+	r := EmptyAABB3()
+	for i := 0; i < 8; i++ {
+		c := b.Min
+		if i&1 != 0 {
+			c.X = b.Max.X
+		}
+		if i&2 != 0 {
+			c.Y = b.Max.Y
+		}
+		if i&4 != 0 {
+			c.Z = b.Max.Z
+		}
+		r = r.Extend(m.MulPoint(c))
+	}
+	return r
+}
@@ -0,0 +1,78 @@
+package core
+
+import "testing"
+import "unsafe"
+
+func TestAABB3Layout(t *testing.T) {
+	// std430 struct { vec3 min; vec3 max; }
+	if offset := unsafe.Offsetof(AABB3{}.Max); offset != 16 {
+		t.Errorf("offset of Max is %v, want 16", offset)
+	}
+	if size := unsafe.Sizeof(AABB3{}); size != 32 {
+		t.Errorf("size is %v, want 32", size)
+	}
+	if size := unsafe.Sizeof(AABB2{}); size != 16 {
+		t.Errorf("size of AABB2 is %v, want 16", size)
+	}
+}
+
+func TestAABB3(t *testing.T) {
+	if !EmptyAABB3().Empty() || !AABB3Of().Empty() {
+		t.Errorf("box of no points isn't empty")
+	}
+
+	b := AABB3Of(Vec3{X: 1, Y: 2, Z: 3}, Vec3{X: -1, Y: 0, Z: 5})
+	if b.Min != (Vec3{X: -1, Y: 0, Z: 3}) || b.Max != (Vec3{X: 1, Y: 2, Z: 5}) {
+		t.Errorf("box of points is %v - %v", b.Min, b.Max)
+	}
+	if b.Center() != (Vec3{X: 0, Y: 1, Z: 4}) || b.Size() != (Vec3{X: 2, Y: 2, Z: 2}) {
+		t.Errorf("center %v, size %v", b.Center(), b.Size())
+	}
+	if !b.Contains(Vec3{X: 1, Y: 1, Z: 4}) || b.Contains(Vec3{X: 1.5, Y: 1, Z: 4}) {
+		t.Errorf("wrong containment")
+	}
+	if !b.Intersects(AABB3Of(Vec3{X: 1, Y: 2, Z: 5}, Vec3{X: 3, Y: 3, Z: 6})) {
+		t.Errorf("boxes touching at corner don't intersect")
+	}
+	if b.Intersects(AABB3Of(Vec3{X: 1.1, Y: 0, Z: 3}, Vec3{X: 3, Y: 3, Z: 6})) {
+		t.Errorf("separated boxes intersect")
+	}
+	if u := b.Union(AABB3Of(Vec3{X: 4, Y: -1, Z: 4})); u.Min != (Vec3{X: -1, Y: -1, Z: 3}) || u.Max != (Vec3{X: 4, Y: 2, Z: 5}) {
+		t.Errorf("union is %v - %v", u.Min, u.Max)
+	}
+}
+
+func TestAABB3Transform(t *testing.T) {
+	b := AABB3Of(Vec3{X: -1, Y: -1, Z: -1}, Vec3{X: 1, Y: 1, Z: 1})
+
+	if got := b.Transform(Identity4()); got != b {
+		t.Errorf("identity transforms box to %v - %v", got.Min, got.Max)
+	}
+
+	translate := Identity4()
+	translate[12], translate[13], translate[14] = 1, 2, 3
+	if got := b.Transform(translate); !nearVec3(got.Min, Vec3{X: 0, Y: 1, Z: 2}) || !nearVec3(got.Max, Vec3{X: 2, Y: 3, Z: 4}) {
+		t.Errorf("translated box is %v - %v", got.Min, got.Max)
+	}
+
+	// rotation by 45 degrees around z widens box by sqrt(2) along x and y
+	c, s := float32(0.70710678), float32(0.70710678)
+	rotate := Mat4{c, s, 0, 0, -s, c, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1}
+	got := b.Transform(rotate)
+	if !nearVec3(got.Max, Vec3{X: 2 * c, Y: 2 * c, Z: 1}) || !nearVec3(got.Min, Vec3{X: -2 * c, Y: -2 * c, Z: -1}) {
+		t.Errorf("rotated box is %v - %v", got.Min, got.Max)
+	}
+}
+
+func TestAABB2(t *testing.T) {
+	b := AABB2Of(Vec2{X: 0, Y: 0}, Vec2{X: 2, Y: 1})
+	if !b.Contains(Vec2{X: 1, Y: 1}) || b.Contains(Vec2{X: -0.1, Y: 0.5}) {
+		t.Errorf("wrong containment")
+	}
+	if b.Extend(Vec2{X: -1, Y: 3}) != (AABB2{Min: Vec2{X: -1, Y: 0}, Max: Vec2{X: 2, Y: 3}}) {
+		t.Errorf("wrong extension")
+	}
+	if !EmptyAABB2().Empty() || b.Empty() {
+		t.Errorf("wrong emptiness")
+	}
+}
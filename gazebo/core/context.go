@@ -0,0 +1,35 @@
+package core
+
+import "log"
+import "github.com/go-gl/gl/v4.6-core/gl"
+
+const (
+	GLSL_VERSION       = 460 // version of shaders, in GLSL #version notation
+	MESA_OVERRIDE_HINT = ", with Mesa llvmpipe set MESA_GL_VERSION_OVERRIDE=4.6 MESA_GLSL_VERSION_OVERRIDE=460"
+)
+
+// environment variables making Mesa llvmpipe, which provides OpenGL 4.5, run 4.6 shaders
+var MesaOverrides = map[string]string{
+	"MESA_GL_VERSION_OVERRIDE":   "4.6",
+	"MESA_GLSL_VERSION_OVERRIDE": "460",
+}
+
+// OpenGL context particle systems run in, either a window or an offscreen surface
+type Context interface {
+	MakeCurrent()
+	SwapBuffers()
+	ShouldClose() bool
+	Size() (width, height int) // size of default framebuffer
+	Destroy()
+}
+
+// loads OpenGL functions, must be called with some context made current
+func InitGL() error {
+	if err := gl.Init(); err != nil {
+		return err
+	}
+	log.Println("OpenGL version", gl.GoStr(gl.GetString(gl.VERSION)))
+	log.Println("OpenGL renderer", gl.GoStr(gl.GetString(gl.RENDERER)))
+	gl.Enable(gl.PROGRAM_POINT_SIZE)
+	return GetError()
+}
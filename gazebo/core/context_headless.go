@@ -0,0 +1,222 @@
+//go:build linux
+// +build linux
+
+package core
+
+// #cgo LDFLAGS: -lEGL
+// #include <stdlib.h>
+// #include <string.h>
+// #include <EGL/egl.h>
+// #include <EGL/eglext.h>
+//
+// // prefers Mesa surfaceless platform, which needs neither display server nor GPU
+// static EGLDisplay headlessDisplay() {
+//     const char *extensions = eglQueryString(EGL_NO_DISPLAY, EGL_EXTENSIONS);
+//     PFNEGLGETPLATFORMDISPLAYEXTPROC getPlatformDisplay =
+//         (PFNEGLGETPLATFORMDISPLAYEXTPROC)eglGetProcAddress("eglGetPlatformDisplayEXT");
+//     if (extensions != NULL && strstr(extensions, "EGL_MESA_platform_surfaceless") != NULL && getPlatformDisplay != NULL) {
+//         return getPlatformDisplay(EGL_PLATFORM_SURFACELESS_MESA, EGL_DEFAULT_DISPLAY, NULL);
+//     }
+//     return eglGetDisplay(EGL_DEFAULT_DISPLAY);
+// }
+//
+// static EGLBoolean pbufferConfig(EGLDisplay display, EGLConfig *config) {
+//     const EGLint attribs[] = {
+//         EGL_SURFACE_TYPE, EGL_PBUFFER_BIT,
+//         EGL_RENDERABLE_TYPE, EGL_OPENGL_BIT,
+//         EGL_RED_SIZE, 8, EGL_GREEN_SIZE, 8, EGL_BLUE_SIZE, 8, EGL_ALPHA_SIZE, 8,
+//         EGL_DEPTH_SIZE, 24,
+//         EGL_NONE,
+//     };
+//     EGLint count = 0;
+//     return eglChooseConfig(display, attribs, config, 1, &count) && count > 0;
+// }
+//
+// // config of OpenGL contexts without surfaces, used with EGL_KHR_surfaceless_context
+// static EGLBoolean surfacelessConfig(EGLDisplay display, EGLConfig *config) {
+//     const EGLint attribs[] = {
+//         EGL_SURFACE_TYPE, 0,
+//         EGL_RENDERABLE_TYPE, EGL_OPENGL_BIT,
+//         EGL_NONE,
+//     };
+//     EGLint count = 0;
+//     return eglChooseConfig(display, attribs, config, 1, &count) && count > 0;
+// }
+//
+// static EGLBoolean hasExtension(EGLDisplay display, const char *name) {
+//     const char *extensions = eglQueryString(display, EGL_EXTENSIONS);
+//     return extensions != NULL && strstr(extensions, name) != NULL;
+// }
+//
+// static EGLConfig noConfig() {
+//     return EGL_NO_CONFIG_KHR;
+// }
+//
+// typedef const unsigned char *(*getStringProc)(unsigned int);
+//
+// // GL_SHADING_LANGUAGE_VERSION of current context, NULL if glGetString isn't available
+// static const char *glslVersion() {
+//     getStringProc getString = (getStringProc)eglGetProcAddress("glGetString");
+//     if (getString == NULL) {
+//         return NULL;
+//     }
+//     return (const char *)getString(0x8B8C);
+// }
+//
+// static EGLSurface pbufferSurface(EGLDisplay display, EGLConfig config, EGLint width, EGLint height) {
+//     const EGLint attribs[] = { EGL_WIDTH, width, EGL_HEIGHT, height, EGL_NONE };
+//     return eglCreatePbufferSurface(display, config, attribs);
+// }
+//
+// static EGLContext coreContext(EGLDisplay display, EGLConfig config, EGLint major, EGLint minor) {
+//     const EGLint attribs[] = {
+//         EGL_CONTEXT_MAJOR_VERSION, major,
+//         EGL_CONTEXT_MINOR_VERSION, minor,
+//         EGL_CONTEXT_OPENGL_PROFILE_MASK, EGL_CONTEXT_OPENGL_CORE_PROFILE_BIT,
+//         EGL_NONE,
+//     };
+//     return eglCreateContext(display, config, EGL_NO_CONTEXT, attribs);
+// }
+import "C"
+
+import "fmt"
+import "log"
+import "unsafe"
+
+// Offscreen context backed by EGL pbuffer surface (or no surface at all if pbuffers
+// are not supported), works without display server, e.g. on Mesa llvmpipe.
+// Note that llvmpipe provides OpenGL 4.5 only, run it with
+// MESA_GL_VERSION_OVERRIDE=4.6 MESA_GLSL_VERSION_OVERRIDE=460 to load 4.6 shaders,
+// without them context creation fails instead of shader compilation.
+type HeadlessContext struct {
+	display C.EGLDisplay
+	surface C.EGLSurface
+	context C.EGLContext
+	width   int
+	height  int
+}
+
+func eglError(call string) error {
+	return fmt.Errorf("%v failed: EGL error 0x%x", call, int(C.eglGetError()))
+}
+
+// creates offscreen context with `width`x`height` default framebuffer and makes it current
+func NewHeadlessContext(width, height int) (*HeadlessContext, error) {
+	hc := HeadlessContext{width: width, height: height}
+
+	hc.display = C.headlessDisplay()
+	if hc.display == C.EGLDisplay(C.EGL_NO_DISPLAY) {
+		return nil, eglError("eglGetDisplay")
+	}
+
+	var major, minor C.EGLint
+	if C.eglInitialize(hc.display, &major, &minor) == C.EGL_FALSE {
+		return nil, eglError("eglInitialize")
+	}
+	log.Printf("EGL version %v.%v\n", major, minor)
+
+	if C.eglBindAPI(C.EGL_OPENGL_API) == C.EGL_FALSE {
+		C.eglTerminate(hc.display)
+		return nil, eglError("eglBindAPI")
+	}
+
+	var config C.EGLConfig
+	if C.pbufferConfig(hc.display, &config) == C.EGL_TRUE {
+		hc.surface = C.pbufferSurface(hc.display, config, C.EGLint(width), C.EGLint(height))
+		if hc.surface == C.EGLSurface(C.EGL_NO_SURFACE) {
+			C.eglTerminate(hc.display)
+			return nil, eglError("eglCreatePbufferSurface")
+		}
+	} else {
+		hc.surface = C.EGLSurface(C.EGL_NO_SURFACE)
+		if !hasExtension(hc.display, "EGL_KHR_surfaceless_context") {
+			C.eglTerminate(hc.display)
+			return nil, fmt.Errorf("EGL supports neither pbuffers nor EGL_KHR_surfaceless_context")
+		}
+		switch {
+		case C.surfacelessConfig(hc.display, &config) == C.EGL_TRUE:
+		case hasExtension(hc.display, "EGL_KHR_no_config_context"):
+			config = C.noConfig()
+		default:
+			C.eglTerminate(hc.display)
+			return nil, fmt.Errorf("EGL has no config of OpenGL contexts and doesn't support EGL_KHR_no_config_context")
+		}
+		log.Printf("EGL pbuffers are not supported, use surfaceless context\n")
+	}
+
+	hc.context = C.coreContext(hc.display, config, 4, 6)
+	if hc.context == C.EGLContext(C.EGL_NO_CONTEXT) {
+		err := fmt.Errorf("OpenGL 4.6 core context: %w%v", eglError("eglCreateContext"), MESA_OVERRIDE_HINT)
+		hc.Destroy()
+		return nil, err
+	}
+
+	if C.eglMakeCurrent(hc.display, hc.surface, hc.surface, hc.context) == C.EGL_FALSE {
+		err := eglError("eglMakeCurrent")
+		hc.Destroy()
+		return nil, err
+	}
+
+	// driver may report 4.6 context while its GLSL is older, e.g. if only
+	// MESA_GL_VERSION_OVERRIDE is set, then shaders fail to compile much later
+	version := C.glslVersion()
+	if version == nil {
+		hc.Destroy()
+		return nil, fmt.Errorf("failed to query GLSL version")
+	}
+	if err := checkGLSLVersion(C.GoString(version)); err != nil {
+		hc.Destroy()
+		return nil, err
+	}
+
+	return &hc, nil
+}
+
+func hasExtension(display C.EGLDisplay, name string) bool {
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+	return C.hasExtension(display, cname) == C.EGL_TRUE
+}
+
+// fails if shading language of current context is older than `#version 460` of shaders
+// `version` is GL_SHADING_LANGUAGE_VERSION string, e.g. "4.50" or "4.60 NVIDIA"
+func checkGLSLVersion(version string) error {
+	var major, minor int
+	if _, err := fmt.Sscanf(version, "%d.%d", &major, &minor); err != nil {
+		return fmt.Errorf("failed to parse GLSL version %q: %w", version, err)
+	}
+	if major*100+minor < GLSL_VERSION {
+		return fmt.Errorf("GLSL version %v is older than #version %v of shaders%v", version, GLSL_VERSION, MESA_OVERRIDE_HINT)
+	}
+	return nil
+}
+
+func (hc *HeadlessContext) MakeCurrent() {
+	C.eglMakeCurrent(hc.display, hc.surface, hc.surface, hc.context)
+}
+
+func (hc *HeadlessContext) SwapBuffers() {
+	if hc.surface != C.EGLSurface(C.EGL_NO_SURFACE) {
+		C.eglSwapBuffers(hc.display, hc.surface)
+	}
+}
+
+// headless context is never closed by user
+func (hc *HeadlessContext) ShouldClose() bool {
+	return false
+}
+
+func (hc *HeadlessContext) Size() (width, height int) {
+	return hc.width, hc.height
+}
+
+func (hc *HeadlessContext) Destroy() {
+	C.eglMakeCurrent(hc.display, C.EGLSurface(C.EGL_NO_SURFACE), C.EGLSurface(C.EGL_NO_SURFACE), C.EGLContext(C.EGL_NO_CONTEXT))
+	if hc.context != C.EGLContext(C.EGL_NO_CONTEXT) {
+		C.eglDestroyContext(hc.display, hc.context)
+	}
+	if hc.surface != C.EGLSurface(C.EGL_NO_SURFACE) {
+		C.eglDestroySurface(hc.display, hc.surface)
+	}
+	C.eglTerminate(hc.display)
+}
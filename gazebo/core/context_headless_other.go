@@ -0,0 +1,19 @@
+//go:build !linux
+// +build !linux
+
+package core
+
+import "errors"
+
+// Offscreen context, supported on linux only
+type HeadlessContext struct{}
+
+func NewHeadlessContext(width, height int) (*HeadlessContext, error) {
+	return nil, errors.New("headless context is supported on linux only")
+}
+
+func (hc *HeadlessContext) MakeCurrent()              {}
+func (hc *HeadlessContext) SwapBuffers()              {}
+func (hc *HeadlessContext) ShouldClose() bool         { return false }
+func (hc *HeadlessContext) Size() (width, height int) { return 0, 0 }
+func (hc *HeadlessContext) Destroy()                  {}
@@ -0,0 +1,21 @@
+//go:build linux
+// +build linux
+
+package core
+
+import "testing"
+
+func TestCheckGLSLVersion(t *testing.T) {
+	for version, ok := range map[string]bool{
+		"4.60":                  true,
+		"4.60 NVIDIA":           true,
+		"5.00":                  true,
+		"4.50":                  false,
+		"4.50 (Core Profile) x": false,
+		"OpenGL ES GLSL 3.20":   false,
+	} {
+		if err := checkGLSLVersion(version); (err == nil) != ok {
+			t.Errorf("version %q: got error %v, want accepted %v", version, err, ok)
+		}
+	}
+}
@@ -1,5 +1,212 @@
 package core
 
+import "math"
+
+// vectors have the size and layout of GLSL ones, so they can be uploaded as is;
+// in std430 buffers vec2 is aligned to 8 bytes, vec3 and vec4 to 16 bytes,
+// Go aligns all of them to 4 bytes, so structs shared with shaders place vec3
+// before a scalar, like Particle3 does, or add padding fields explicitly;
+// ValidateBufferLayout in package inspect reports mismatches
 type Vec2 struct {
 	X, Y float32
 }
+
+type Vec3 struct {
+	X, Y, Z float32
+}
+
+type Vec4 struct {
+	X, Y, Z, W float32
+}
+
+type IVec2 struct {
+	X, Y int32
+}
+
+type IVec3 struct {
+	X, Y, Z int32
+}
+
+type IVec4 struct {
+	X, Y, Z, W int32
+}
+
+type UVec2 struct {
+	X, Y uint32
+}
+
+type UVec3 struct {
+	X, Y, Z uint32
+}
+
+type UVec4 struct {
+	X, Y, Z, W uint32
+}
+
+// boolean vectors aren't laid out like GLSL ones, which use 4 bytes per component,
+// uniforms convert them to ints
+type BVec2 struct {
+	X, Y bool
+}
+
+type BVec3 struct {
+	X, Y, Z bool
+}
+
+type BVec4 struct {
+	X, Y, Z, W bool
+}
+
+// matrices are stored in column-major order, as GLSL expects
+type Mat2 [2 * 2]float32
+type Mat3 [3 * 3]float32
+type Mat4 [4 * 4]float32
+
+func (v Vec2) Add(o Vec2) Vec2 {
+	return Vec2{X: v.X + o.X, Y: v.Y + o.Y}
+}
+
+func (v Vec2) Sub(o Vec2) Vec2 {
+	return Vec2{X: v.X - o.X, Y: v.Y - o.Y}
+}
+
+func (v Vec2) Scale(s float32) Vec2 {
+	return Vec2{X: v.X * s, Y: v.Y * s}
+}
+
+// component-wise product
+func (v Vec2) Mul(o Vec2) Vec2 {
+	return Vec2{X: v.X * o.X, Y: v.Y * o.Y}
+}
+
+func (v Vec2) Dot(o Vec2) float32 {
+	return v.X*o.X + v.Y*o.Y
+}
+
+// z component of cross product of vectors extended with z = 0
+func (v Vec2) Cross(o Vec2) float32 {
+	return v.X*o.Y - v.Y*o.X
+}
+
+func (v Vec2) Length() float32 {
+	return float32(math.Sqrt(float64(v.Dot(v))))
+}
+
+// unit vector of the same direction, zero vector is returned as is
+func (v Vec2) Normalize() Vec2 {
+	l := v.Length()
+	if l == 0 {
+		return v
+	}
+	return Vec2{X: v.X / l, Y: v.Y / l}
+}
+
+// component-wise minimum
+func (v Vec2) Min(o Vec2) Vec2 {
+	return Vec2{X: min32(v.X, o.X), Y: min32(v.Y, o.Y)}
+}
+
+// component-wise maximum
+func (v Vec2) Max(o Vec2) Vec2 {
+	return Vec2{X: max32(v.X, o.X), Y: max32(v.Y, o.Y)}
+}
+
+func (v Vec3) Add(o Vec3) Vec3 {
+	return Vec3{X: v.X + o.X, Y: v.Y + o.Y, Z: v.Z + o.Z}
+}
+
+func (v Vec3) Sub(o Vec3) Vec3 {
+	return Vec3{X: v.X - o.X, Y: v.Y - o.Y, Z: v.Z - o.Z}
+}
+
+func (v Vec3) Scale(s float32) Vec3 {
+	return Vec3{X: v.X * s, Y: v.Y * s, Z: v.Z * s}
+}
+
+// component-wise product
+func (v Vec3) Mul(o Vec3) Vec3 {
+	return Vec3{X: v.X * o.X, Y: v.Y * o.Y, Z: v.Z * o.Z}
+}
+
+func (v Vec3) Dot(o Vec3) float32 {
+	return v.X*o.X + v.Y*o.Y + v.Z*o.Z
+}
+
+func (v Vec3) Cross(o Vec3) Vec3 {
+	return Vec3{X: v.Y*o.Z - v.Z*o.Y, Y: v.Z*o.X - v.X*o.Z, Z: v.X*o.Y - v.Y*o.X}
+}
+
+func (v Vec3) Length() float32 {
+	return float32(math.Sqrt(float64(v.Dot(v))))
+}
+
+// unit vector of the same direction, zero vector is returned as is
+func (v Vec3) Normalize() Vec3 {
+	l := v.Length()
+	if l == 0 {
+		return v
+	}
+	return Vec3{X: v.X / l, Y: v.Y / l, Z: v.Z / l}
+}
+
+// component-wise minimum
+func (v Vec3) Min(o Vec3) Vec3 {
+	return Vec3{X: min32(v.X, o.X), Y: min32(v.Y, o.Y), Z: min32(v.Z, o.Z)}
+}
+
+// component-wise maximum
+func (v Vec3) Max(o Vec3) Vec3 {
+	return Vec3{X: max32(v.X, o.X), Y: max32(v.Y, o.Y), Z: max32(v.Z, o.Z)}
+}
+
+// vector extended with w, e.g. 1 for points and 0 for directions
+func (v Vec3) Vec4(w float32) Vec4 {
+	return Vec4{X: v.X, Y: v.Y, Z: v.Z, W: w}
+}
+
+func (v Vec4) Add(o Vec4) Vec4 {
+	return Vec4{X: v.X + o.X, Y: v.Y + o.Y, Z: v.Z + o.Z, W: v.W + o.W}
+}
+
+func (v Vec4) Sub(o Vec4) Vec4 {
+	return Vec4{X: v.X - o.X, Y: v.Y - o.Y, Z: v.Z - o.Z, W: v.W - o.W}
+}
+
+func (v Vec4) Scale(s float32) Vec4 {
+	return Vec4{X: v.X * s, Y: v.Y * s, Z: v.Z * s, W: v.W * s}
+}
+
+// component-wise product
+func (v Vec4) Mul(o Vec4) Vec4 {
+	return Vec4{X: v.X * o.X, Y: v.Y * o.Y, Z: v.Z * o.Z, W: v.W * o.W}
+}
+
+func (v Vec4) Dot(o Vec4) float32 {
+	return v.X*o.X + v.Y*o.Y + v.Z*o.Z + v.W*o.W
+}
+
+func (v Vec4) Length() float32 {
+	return float32(math.Sqrt(float64(v.Dot(v))))
+}
+
+// unit vector of the same direction, zero vector is returned as is
+func (v Vec4) Normalize() Vec4 {
+	l := v.Length()
+	if l == 0 {
+		return v
+	}
+	return Vec4{X: v.X / l, Y: v.Y / l, Z: v.Z / l, W: v.W / l}
+}
+
+// x, y and z components
+func (v Vec4) Vec3() Vec3 {
+	return Vec3{X: v.X, Y: v.Y, Z: v.Z}
+}
+
+func min32(a, b float32) float32 {
+	return float32(math.Min(float64(a), float64(b)))
+}
+
+func max32(a, b float32) float32 {
+	return float32(math.Max(float64(a), float64(b)))
+}
@@ -0,0 +1,221 @@
+package core
+
+import "math"
+
+// mat3 in std430 buffers, every column is padded to vec4
+type Mat3Std430 [3 * 4]float32
+
+func Identity3() Mat3 {
+	return Mat3{
+		1, 0, 0,
+		0, 1, 0,
+		0, 0, 1,
+	}
+}
+
+func Identity4() Mat4 {
+	return Mat4{
+		1, 0, 0, 0,
+		0, 1, 0, 0,
+		0, 0, 1, 0,
+		0, 0, 0, 1,
+	}
+}
+
+// element at row `r` and column `c`
+func (m Mat3) At(r, c int) float32 {
+	return m[c*3+r]
+}
+
+// element at row `r` and column `c`
+func (m Mat4) At(r, c int) float32 {
+	return m[c*4+r]
+}
+
+// product m * n, applying it to vector is the same as applying n and then m
+func (m Mat3) Mul(n Mat3) Mat3 {
+	var p Mat3
+	for c := 0; c < 3; c++ {
+		for r := 0; r < 3; r++ {
+			for k := 0; k < 3; k++ {
+				p[c*3+r] += m[k*3+r] * n[c*3+k]
+			}
+		}
+	}
+	return p
+}
+
+// product m * n, applying it to vector is the same as applying n and then m
+func (m Mat4) Mul(n Mat4) Mat4 {
+	var p Mat4
+	for c := 0; c < 4; c++ {
+		for r := 0; r < 4; r++ {
+			for k := 0; k < 4; k++ {
+				p[c*4+r] += m[k*4+r] * n[c*4+k]
+			}
+		}
+	}
+	return p
+}
+
+func (m Mat3) MulVec(v Vec3) Vec3 {
+	return Vec3{
+		X: m[0]*v.X + m[3]*v.Y + m[6]*v.Z,
+		Y: m[1]*v.X + m[4]*v.Y + m[7]*v.Z,
+		Z: m[2]*v.X + m[5]*v.Y + m[8]*v.Z,
+	}
+}
+
+func (m Mat4) MulVec(v Vec4) Vec4 {
+	return Vec4{
+		X: m[0]*v.X + m[4]*v.Y + m[8]*v.Z + m[12]*v.W,
+		Y: m[1]*v.X + m[5]*v.Y + m[9]*v.Z + m[13]*v.W,
+		Z: m[2]*v.X + m[6]*v.Y + m[10]*v.Z + m[14]*v.W,
+		W: m[3]*v.X + m[7]*v.Y + m[11]*v.Z + m[15]*v.W,
+	}
+}
+
+// transforms point, dividing result by w if it isn't 1
+func (m Mat4) MulPoint(p Vec3) Vec3 {
+	v := m.MulVec(p.Vec4(1))
+	if v.W != 0 && v.W != 1 {
+		return v.Vec3().Scale(1 / v.W)
+	}
+	return v.Vec3()
+}
+
+func (m Mat3) Transpose() Mat3 {
+	var t Mat3
+	for c := 0; c < 3; c++ {
+		for r := 0; r < 3; r++ {
+			t[r*3+c] = m[c*3+r]
+		}
+	}
+	return t
+}
+
+func (m Mat4) Transpose() Mat4 {
+	var t Mat4
+	for c := 0; c < 4; c++ {
+		for r := 0; r < 4; r++ {
+			t[r*4+c] = m[c*4+r]
+		}
+	}
+	return t
+}
+
+// inverse matrix, false if m is singular
+func (m Mat3) Inverse() (Mat3, bool) {
+	var inv Mat3
+	ok := invert(m[:], inv[:], 3)
+	return inv, ok
+}
+
+// inverse matrix, false if m is singular
+func (m Mat4) Inverse() (Mat4, bool) {
+	var inv Mat4
+	ok := invert(m[:], inv[:], 4)
+	return inv, ok
+}
+
+// upper-left 3x3 part, i.e. linear part of affine transformation
+func (m Mat4) Mat3() Mat3 {
+	return Mat3{
+		m[0], m[1], m[2],
+		m[4], m[5], m[6],
+		m[8], m[9], m[10],
+	}
+}
+
+// matrix in layout of mat3 in std430 buffers, uniforms take Mat3 as is
+func (m Mat3) Std430() Mat3Std430 {
+	return Mat3Std430{
+		m[0], m[1], m[2], 0,
+		m[3], m[4], m[5], 0,
+		m[6], m[7], m[8], 0,
+	}
+}
+
+// inverts n x n matrix `m` into `inv` by Gauss-Jordan elimination with partial pivoting,
+// storage order doesn't matter since inverse of transposed matrix is transposed inverse
+func invert(m, inv []float32, n int) bool {
+	a := make([]float64, n*n)
+	b := make([]float64, n*n)
+	for i := range a {
+		a[i] = float64(m[i])
+	}
+	for i := 0; i < n; i++ {
+		b[i*n+i] = 1
+	}
+
+	for c := 0; c < n; c++ {
+		pivot := c
+		for r := c + 1; r < n; r++ {
+			if math.Abs(a[r*n+c]) > math.Abs(a[pivot*n+c]) {
+				pivot = r
+			}
+		}
+		if a[pivot*n+c] == 0 {
+			return false
+		}
+		for k := 0; k < n; k++ {
+			a[c*n+k], a[pivot*n+k] = a[pivot*n+k], a[c*n+k]
+			b[c*n+k], b[pivot*n+k] = b[pivot*n+k], b[c*n+k]
+		}
+
+		d := a[c*n+c]
+		for k := 0; k < n; k++ {
+			a[c*n+k] /= d
+			b[c*n+k] /= d
+		}
+		for r := 0; r < n; r++ {
+			if r == c {
+				continue
+			}
+			f := a[r*n+c]
+			for k := 0; k < n; k++ {
+				a[r*n+k] -= f * a[c*n+k]
+				b[r*n+k] -= f * b[c*n+k]
+			}
+		}
+	}
+
+	for i := range b {
+		inv[i] = float32(b[i])
+	}
+	return true
+}
+
+// projection matrix of box [left, right] x [bottom, top] x [-near, -far], like glOrtho
+func Orthographic(left, right, bottom, top, near, far float32) Mat4 {
+	return Mat4{
+		2 / (right - left), 0, 0, 0,
+		0, 2 / (top - bottom), 0, 0,
+		0, 0, -2 / (far - near), 0,
+		-(right + left) / (right - left), -(top + bottom) / (top - bottom), -(far + near) / (far - near), 1,
+	}
+}
+
+// projection matrix of vertical field of view `fovY` in radians, like gluPerspective
+func Perspective(fovY, aspect, near, far float32) Mat4 {
+	f := float32(1 / math.Tan(float64(fovY)/2))
+	return Mat4{
+		f / aspect, 0, 0, 0,
+		0, f, 0, 0,
+		0, 0, (far + near) / (near - far), -1,
+		0, 0, 2 * far * near / (near - far), 0,
+	}
+}
+
+// view matrix of camera at eye looking at center, like gluLookAt
+func LookAt(eye, center, up Vec3) Mat4 {
+	f := center.Sub(eye).Normalize()
+	s := f.Cross(up).Normalize()
+	u := s.Cross(f)
+	return Mat4{
+		s.X, u.X, -f.X, 0,
+		s.Y, u.Y, -f.Y, 0,
+		s.Z, u.Z, -f.Z, 0,
+		-s.Dot(eye), -u.Dot(eye), f.Dot(eye), 1,
+	}
+}
@@ -0,0 +1,131 @@
+package core
+
+import "math"
+import "testing"
+
+const EPS = 1e-5 // tolerance of single precision results
+
+func nearVec3(a, b Vec3) bool {
+	return a.Sub(b).Length() <= EPS*float32(math.Max(1, float64(b.Length())))
+}
+
+func nearMat4(a, b Mat4) bool {
+	for i := range a {
+		if math.Abs(float64(a[i]-b[i])) > EPS {
+			return false
+		}
+	}
+	return true
+}
+
+func TestMat4Inverse(t *testing.T) {
+	matrices := []Mat4{
+		Identity4(),
+		LookAt(Vec3{X: 1, Y: 2, Z: 3}, Vec3{}, Vec3{X: 0, Y: 1, Z: 0}),
+		Perspective(1, 1.5, 0.1, 10),
+		Orthographic(-2, 3, -1, 4, -1, 5),
+		Perspective(1, 1.5, 0.1, 10).Mul(LookAt(Vec3{X: -1, Y: 0.5, Z: 2}, Vec3{X: 0, Y: 0, Z: -1}, Vec3{X: 0, Y: 1, Z: 0})),
+	}
+	for i, m := range matrices {
+		inverse, ok := m.Inverse()
+		if !ok {
+			t.Errorf("matrix %v: not invertible", i)
+			continue
+		}
+		if p := m.Mul(inverse); !nearMat4(p, Identity4()) {
+			t.Errorf("matrix %v: m * inverse = %v", i, p)
+		}
+		if p := inverse.Mul(m); !nearMat4(p, Identity4()) {
+			t.Errorf("matrix %v: inverse * m = %v", i, p)
+		}
+	}
+
+	if _, ok := (Mat4{}).Inverse(); ok {
+		t.Errorf("zero matrix is invertible")
+	}
+}
+
+func TestMat3Inverse(t *testing.T) {
+	m := Mat3{2, 0, 0, 1, 3, 0, 0, 1, 4}
+	inverse, ok := m.Inverse()
+	if !ok {
+		t.Fatalf("not invertible")
+	}
+	p := m.Mul(inverse)
+	for i := range p {
+		if math.Abs(float64(p[i]-Identity3()[i])) > EPS {
+			t.Fatalf("m * inverse = %v", p)
+		}
+	}
+	if _, ok := (Mat3{1, 2, 3, 2, 4, 6, 0, 0, 1}).Inverse(); ok {
+		t.Errorf("singular matrix is invertible")
+	}
+}
+
+func TestMat4MulIsComposition(t *testing.T) {
+	a := LookAt(Vec3{X: 1, Y: 2, Z: 3}, Vec3{}, Vec3{X: 0, Y: 1, Z: 0})
+	b := Orthographic(-2, 3, -1, 4, -1, 5)
+	v := Vec4{X: 0.3, Y: -0.7, Z: 1.1, W: 1}
+	if got, want := a.Mul(b).MulVec(v), a.MulVec(b.MulVec(v)); !nearVec3(got.Vec3(), want.Vec3()) || math.Abs(float64(got.W-want.W)) > EPS {
+		t.Errorf("(a * b) v = %v, a (b v) = %v", got, want)
+	}
+	if got := a.Transpose().Transpose(); got != a {
+		t.Errorf("transposed twice: %v", got)
+	}
+	if got := a.At(0, 3); got != a[12] {
+		t.Errorf("At(0, 3) = %v, want column-major element %v", got, a[12])
+	}
+}
+
+func TestLookAt(t *testing.T) {
+	eye, center, up := Vec3{X: 1, Y: 2, Z: 3}, Vec3{X: -1, Y: 0, Z: 1}, Vec3{X: 0, Y: 1, Z: 0}
+	view := LookAt(eye, center, up)
+
+	if got := view.MulPoint(eye); !nearVec3(got, Vec3{}) {
+		t.Errorf("eye is at %v in view space, want origin", got)
+	}
+	// camera looks along -z
+	distance := center.Sub(eye).Length()
+	if got := view.MulPoint(center); !nearVec3(got, Vec3{X: 0, Y: 0, Z: -distance}) {
+		t.Errorf("center is at %v in view space, want (0, 0, %v)", got, -distance)
+	}
+	// rotation part is orthonormal
+	r := view.Mat3()
+	if p := r.Mul(r.Transpose()); !nearVec3(p.MulVec(Vec3{X: 1, Y: 2, Z: 3}), Vec3{X: 1, Y: 2, Z: 3}) {
+		t.Errorf("rotation isn't orthonormal: %v", p)
+	}
+}
+
+func TestPerspective(t *testing.T) {
+	near, far := float32(0.5), float32(20)
+	p := Perspective(math.Pi/2, 2, near, far)
+
+	if got := p.MulPoint(Vec3{X: 0, Y: 0, Z: -near}); !nearVec3(got, Vec3{X: 0, Y: 0, Z: -1}) {
+		t.Errorf("point on near plane maps to %v, want z = -1", got)
+	}
+	if got := p.MulPoint(Vec3{X: 0, Y: 0, Z: -far}); !nearVec3(got, Vec3{X: 0, Y: 0, Z: 1}) {
+		t.Errorf("point on far plane maps to %v, want z = 1", got)
+	}
+	// vertical field of view of 90 degrees, horizontal one widened by aspect ratio
+	if got := p.MulPoint(Vec3{X: 2, Y: 1, Z: -1}); !nearVec3(got.Vec4(0).Vec3(), Vec3{X: 1, Y: 1, Z: got.Z}) {
+		t.Errorf("corner of frustum maps to %v, want (1, 1)", got)
+	}
+}
+
+func TestOrthographic(t *testing.T) {
+	o := Orthographic(-2, 4, -1, 3, -1, 5)
+	if got := o.MulPoint(Vec3{X: -2, Y: -1, Z: 1}); !nearVec3(got, Vec3{X: -1, Y: -1, Z: -1}) {
+		t.Errorf("near lower left corner maps to %v", got)
+	}
+	if got := o.MulPoint(Vec3{X: 4, Y: 3, Z: -5}); !nearVec3(got, Vec3{X: 1, Y: 1, Z: 1}) {
+		t.Errorf("far upper right corner maps to %v", got)
+	}
+}
+
+func TestMat3Std430(t *testing.T) {
+	m := Mat3{1, 2, 3, 4, 5, 6, 7, 8, 9}
+	want := Mat3Std430{1, 2, 3, 0, 4, 5, 6, 0, 7, 8, 9, 0}
+	if got := m.Std430(); got != want {
+		t.Errorf("std430 layout is %v, want %v", got, want)
+	}
+}
@@ -1,20 +1,37 @@
 package core
 
+import "errors"
+import "fmt"
+import "image"
+import "time"
 import "unsafe"
 import "github.com/go-gl/gl/v4.6-core/gl"
 
 type TransformFeedbackObject uint32
 type VertexArrayObject uint32
 type VertexBufferObject uint32
+type Texture2D uint32
+type Renderbuffer uint32
+type FramebufferObject uint32
+type QueryObject uint32
+
+var ErrFramebufferIncomplete = errors.New("framebuffer is incomplete")
 
 // Methods of Transform Feedback Object
 
 func MakeTransformFeedbackObject() TransformFeedbackObject {
 	var tfb uint32
 	gl.GenTransformFeedbacks(1, &tfb)
+	track("TransformFeedbackObject", tfb)
 	return TransformFeedbackObject(tfb)
 }
 
+func (tfb TransformFeedbackObject) Delete() {
+	untrack("TransformFeedbackObject", uint32(tfb))
+	id := uint32(tfb)
+	gl.DeleteTransformFeedbacks(1, &id)
+}
+
 func (tfb TransformFeedbackObject) Bind() func() {
 	gl.BindTransformFeedback(gl.TRANSFORM_FEEDBACK, uint32(tfb))
 	return func() {
@@ -22,14 +39,19 @@ func (tfb TransformFeedbackObject) Bind() func() {
 	}
 }
 
-func (tfb TransformFeedbackObject) AttachVertexBuffer(vbo VertexBufferObject) func() {
+// binds transform feedback with vertex buffer attached to it, nothing stays bound on error
+func (tfb TransformFeedbackObject) AttachVertexBuffer(vbo VertexBufferObject) (func(), error) {
 	unbind_tfb := tfb.Bind()
 	unbind_vbo := vbo.BindBase(gl.TRANSFORM_FEEDBACK_BUFFER, 0)
-	CheckError()
-	return func() {
+	detach := func() {
 		unbind_vbo()
 		unbind_tfb()
 	}
+	if err := GetError(); err != nil {
+		detach()
+		return nil, err
+	}
+	return detach, nil
 }
 
 // Methods of Vertex Array Object
@@ -37,9 +59,16 @@ func (tfb TransformFeedbackObject) AttachVertexBuffer(vbo VertexBufferObject) fu
 func MakeVertexArrayObject() VertexArrayObject {
 	var vao uint32
 	gl.GenVertexArrays(1, &vao)
+	track("VertexArrayObject", vao)
 	return VertexArrayObject(vao)
 }
 
+func (vao VertexArrayObject) Delete() {
+	untrack("VertexArrayObject", uint32(vao))
+	id := uint32(vao)
+	gl.DeleteVertexArrays(1, &id)
+}
+
 func (vao VertexArrayObject) Bind() func() {
 	gl.BindVertexArray(uint32(vao))
 	return func() {
@@ -55,31 +84,41 @@ func MakeVertexBufferObject(sizeBytes int, data unsafe.Pointer) VertexBufferObje
 	if sizeBytes > 0 {
 		gl.NamedBufferData(vbo, sizeBytes, data, gl.DYNAMIC_DRAW)
 	}
+	track("VertexBufferObject", vbo)
 	return VertexBufferObject(vbo)
 }
 
-func (vbo VertexBufferObject) SetData(data unsafe.Pointer, size uint32) uint32 {
+func (vbo VertexBufferObject) Delete() {
+	untrack("VertexBufferObject", uint32(vbo))
+	id := uint32(vbo)
+	gl.DeleteBuffers(1, &id)
+}
+
+func (vbo VertexBufferObject) SetData(data unsafe.Pointer, size uint32) error {
 	// TODO: consider keeping buffer if it's size is enough
-	CheckError()
+	if err := GetError(); err != nil {
+		return err
+	}
 
 	unbind := vbo.Bind(gl.SHADER_STORAGE_BUFFER)
 	defer unbind()
 
 	gl.BufferData(gl.SHADER_STORAGE_BUFFER, int(size), data, gl.DYNAMIC_DRAW)
 
-	CheckError()
-	return size
+	return GetError()
 }
 
-func (vbo VertexBufferObject) GetData(data unsafe.Pointer, size uint32) {
-	CheckError()
+func (vbo VertexBufferObject) GetData(data unsafe.Pointer, size uint32) error {
+	if err := GetError(); err != nil {
+		return err
+	}
 
 	unbind := vbo.Bind(gl.SHADER_STORAGE_BUFFER)
 	defer unbind()
 
 	gl.GetBufferSubData(gl.SHADER_STORAGE_BUFFER, 0, int(size), data)
 
-	CheckError()
+	return GetError()
 }
 
 func (vbo VertexBufferObject) Bind(target uint32) func() {
@@ -96,11 +135,293 @@ func (vbo VertexBufferObject) BindBase(target, index uint32) func() {
 	}
 }
 
-func (vbo VertexBufferObject) Size() (sizeBytes int32) {
+func (vbo VertexBufferObject) Size() (sizeBytes int32, err error) {
 	unbind := vbo.Bind(gl.ARRAY_BUFFER)
 	defer unbind()
 
 	gl.GetBufferParameteriv(gl.ARRAY_BUFFER, gl.BUFFER_SIZE, &sizeBytes)
-	CheckError()
-	return
+	return sizeBytes, GetError()
+}
+
+// Methods of 2D Texture
+
+// creates texture of immutable size with single mipmap level, e.g. of gl.RGBA8 or gl.DEPTH_COMPONENT24
+// internal format, with linear filtering and coordinates clamped to edge
+func MakeTexture2D(width, height int32, internalFormat uint32) (Texture2D, error) {
+	if err := GetError(); err != nil {
+		return 0, err
+	}
+
+	var tex uint32
+	gl.GenTextures(1, &tex)
+	track("Texture2D", tex)
+
+	unbind := Texture2D(tex).Bind(0)
+	defer unbind()
+
+	gl.TexStorage2D(gl.TEXTURE_2D, 1, internalFormat, width, height)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+
+	return Texture2D(tex), GetError()
+}
+
+func (tex Texture2D) Delete() {
+	untrack("Texture2D", uint32(tex))
+	id := uint32(tex)
+	gl.DeleteTextures(1, &id)
+}
+
+// binds texture to texture unit, e.g. to be sampled by sampler uniform set to `unit`
+func (tex Texture2D) Bind(unit uint32) func() {
+	gl.ActiveTexture(gl.TEXTURE0 + unit)
+	gl.BindTexture(gl.TEXTURE_2D, uint32(tex))
+	return func() {
+		gl.ActiveTexture(gl.TEXTURE0 + unit)
+		gl.BindTexture(gl.TEXTURE_2D, 0)
+		gl.ActiveTexture(gl.TEXTURE0)
+	}
+}
+
+// binds texture to image unit for load/store in shaders
+func (tex Texture2D) BindImage(unit, access, format uint32) func() {
+	gl.BindImageTexture(unit, uint32(tex), 0, false, 0, access, format)
+	return func() {
+		gl.BindImageTexture(unit, 0, 0, false, 0, access, format)
+	}
+}
+
+func (tex Texture2D) Size() (width, height int32, err error) {
+	gl.GetTextureLevelParameteriv(uint32(tex), 0, gl.TEXTURE_WIDTH, &width)
+	gl.GetTextureLevelParameteriv(uint32(tex), 0, gl.TEXTURE_HEIGHT, &height)
+	return width, height, GetError()
+}
+
+// replaces whole texture image with `data` of given pixel format and type, e.g. gl.RGBA and gl.UNSIGNED_BYTE
+func (tex Texture2D) SetData(format, xtype uint32, data unsafe.Pointer) error {
+	if err := GetError(); err != nil {
+		return err
+	}
+
+	width, height, err := tex.Size()
+	if err != nil {
+		return err
+	}
+	unbind := tex.Bind(0)
+	defer unbind()
+
+	gl.PixelStorei(gl.UNPACK_ALIGNMENT, 1)
+	gl.TexSubImage2D(gl.TEXTURE_2D, 0, 0, 0, width, height, format, xtype, data)
+
+	return GetError()
+}
+
+// reads whole texture image to `data` of `size` bytes
+func (tex Texture2D) GetData(format, xtype uint32, data unsafe.Pointer, size uint32) error {
+	if err := GetError(); err != nil {
+		return err
+	}
+
+	gl.PixelStorei(gl.PACK_ALIGNMENT, 1)
+	gl.GetTextureImage(uint32(tex), 0, format, xtype, int32(size), data)
+
+	return GetError()
+}
+
+// Methods of Renderbuffer
+
+// creates renderbuffer of given size and internal format, e.g. gl.DEPTH_COMPONENT24
+func MakeRenderbuffer(width, height int32, internalFormat uint32) (Renderbuffer, error) {
+	if err := GetError(); err != nil {
+		return 0, err
+	}
+
+	var rb uint32
+	gl.GenRenderbuffers(1, &rb)
+	track("Renderbuffer", rb)
+
+	unbind := Renderbuffer(rb).Bind()
+	defer unbind()
+
+	gl.RenderbufferStorage(gl.RENDERBUFFER, internalFormat, width, height)
+
+	return Renderbuffer(rb), GetError()
+}
+
+func (rb Renderbuffer) Delete() {
+	untrack("Renderbuffer", uint32(rb))
+	id := uint32(rb)
+	gl.DeleteRenderbuffers(1, &id)
+}
+
+func (rb Renderbuffer) Bind() func() {
+	gl.BindRenderbuffer(gl.RENDERBUFFER, uint32(rb))
+	return func() {
+		gl.BindRenderbuffer(gl.RENDERBUFFER, 0)
+	}
+}
+
+// Methods of Framebuffer Object, FramebufferObject(0) is the default framebuffer
+
+func MakeFramebufferObject() FramebufferObject {
+	var fbo uint32
+	gl.CreateFramebuffers(1, &fbo)
+	track("FramebufferObject", fbo)
+	return FramebufferObject(fbo)
+}
+
+// deletes framebuffer, attached textures and renderbuffers are not deleted
+func (fbo FramebufferObject) Delete() {
+	untrack("FramebufferObject", uint32(fbo))
+	id := uint32(fbo)
+	gl.DeleteFramebuffers(1, &id)
+}
+
+// binds framebuffer for drawing and reading
+func (fbo FramebufferObject) Bind() func() {
+	gl.BindFramebuffer(gl.FRAMEBUFFER, uint32(fbo))
+	return func() {
+		gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+	}
+}
+
+// attaches texture to attachment point, e.g. gl.COLOR_ATTACHMENT0 or gl.DEPTH_ATTACHMENT
+func (fbo FramebufferObject) AttachTexture(attachment uint32, tex Texture2D) error {
+	unbind := fbo.Bind()
+	defer unbind()
+
+	gl.FramebufferTexture2D(gl.FRAMEBUFFER, attachment, gl.TEXTURE_2D, uint32(tex), 0)
+	return GetError()
+}
+
+func (fbo FramebufferObject) AttachRenderbuffer(attachment uint32, rb Renderbuffer) error {
+	unbind := fbo.Bind()
+	defer unbind()
+
+	gl.FramebufferRenderbuffer(gl.FRAMEBUFFER, attachment, gl.RENDERBUFFER, uint32(rb))
+	return GetError()
+}
+
+// selects color attachments fragment shader outputs are written to
+func (fbo FramebufferObject) SetDrawBuffers(attachments ...uint32) error {
+	if len(attachments) == 0 {
+		gl.NamedFramebufferDrawBuffer(uint32(fbo), gl.NONE)
+	} else {
+		gl.NamedFramebufferDrawBuffers(uint32(fbo), int32(len(attachments)), &attachments[0])
+	}
+	return GetError()
+}
+
+var framebufferStatuses = map[uint32]string{
+	gl.FRAMEBUFFER_UNDEFINED:                     "GL_FRAMEBUFFER_UNDEFINED",
+	gl.FRAMEBUFFER_INCOMPLETE_ATTACHMENT:         "GL_FRAMEBUFFER_INCOMPLETE_ATTACHMENT",
+	gl.FRAMEBUFFER_INCOMPLETE_MISSING_ATTACHMENT: "GL_FRAMEBUFFER_INCOMPLETE_MISSING_ATTACHMENT",
+	gl.FRAMEBUFFER_INCOMPLETE_DRAW_BUFFER:        "GL_FRAMEBUFFER_INCOMPLETE_DRAW_BUFFER",
+	gl.FRAMEBUFFER_INCOMPLETE_READ_BUFFER:        "GL_FRAMEBUFFER_INCOMPLETE_READ_BUFFER",
+	gl.FRAMEBUFFER_UNSUPPORTED:                   "GL_FRAMEBUFFER_UNSUPPORTED",
+	gl.FRAMEBUFFER_INCOMPLETE_MULTISAMPLE:        "GL_FRAMEBUFFER_INCOMPLETE_MULTISAMPLE",
+	gl.FRAMEBUFFER_INCOMPLETE_LAYER_TARGETS:      "GL_FRAMEBUFFER_INCOMPLETE_LAYER_TARGETS",
+}
+
+// checks that framebuffer can be rendered to, returns error wrapping ErrFramebufferIncomplete otherwise
+func (fbo FramebufferObject) CheckStatus() error {
+	status := gl.CheckNamedFramebufferStatus(uint32(fbo), gl.FRAMEBUFFER)
+	if err := GetError(); err != nil {
+		return err
+	}
+	if status == gl.FRAMEBUFFER_COMPLETE {
+		return nil
+	}
+	name, ok := framebufferStatuses[status]
+	if !ok {
+		name = fmt.Sprintf("0x%x", status)
+	}
+	return fmt.Errorf("%w: framebuffer %v status %v", ErrFramebufferIncomplete, uint32(fbo), name)
+}
+
+// reads rectangle of pixels from attachment, e.g. gl.COLOR_ATTACHMENT0 or gl.BACK for default framebuffer,
+// to `data` of `size` bytes
+func (fbo FramebufferObject) ReadPixels(attachment uint32, x, y, width, height int32, format, xtype uint32, data unsafe.Pointer, size uint32) error {
+	if err := GetError(); err != nil {
+		return err
+	}
+
+	gl.BindFramebuffer(gl.READ_FRAMEBUFFER, uint32(fbo))
+	defer gl.BindFramebuffer(gl.READ_FRAMEBUFFER, 0)
+
+	gl.NamedFramebufferReadBuffer(uint32(fbo), attachment)
+	gl.PixelStorei(gl.PACK_ALIGNMENT, 1)
+	gl.ReadnPixels(x, y, width, height, format, xtype, int32(size), data)
+
+	return GetError()
+}
+
+// reads color attachment to image, rows are flipped so that the first row is the top one
+func (fbo FramebufferObject) ReadImage(attachment uint32, width, height int32) (*image.NRGBA, error) {
+	img := image.NewNRGBA(image.Rect(0, 0, int(width), int(height)))
+	if err := fbo.ReadPixels(attachment, 0, 0, width, height, gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(img.Pix), uint32(len(img.Pix))); err != nil {
+		return nil, err
+	}
+
+	row := make([]uint8, img.Stride)
+	for y := 0; y < int(height)/2; y++ {
+		top := img.Pix[y*img.Stride : (y+1)*img.Stride]
+		bottom := img.Pix[(int(height)-1-y)*img.Stride : (int(height)-y)*img.Stride]
+		copy(row, top)
+		copy(top, bottom)
+		copy(bottom, row)
+	}
+	return img, nil
+}
+
+// Methods of Query Object
+
+func MakeQueryObject() QueryObject {
+	var q uint32
+	gl.GenQueries(1, &q)
+	track("QueryObject", q)
+	return QueryObject(q)
+}
+
+func (q QueryObject) Delete() {
+	untrack("QueryObject", uint32(q))
+	id := uint32(q)
+	gl.DeleteQueries(1, &id)
+}
+
+// measures GPU time of commands issued until returned function is called,
+// time elapsed queries can't be nested
+func (q QueryObject) BeginTimeElapsed() func() {
+	gl.BeginQuery(gl.TIME_ELAPSED, uint32(q))
+	return func() {
+		gl.EndQuery(gl.TIME_ELAPSED)
+	}
+}
+
+// records GPU time when all previously issued commands are completed,
+// Result is the time in nanoseconds since an arbitrary moment
+func (q QueryObject) Timestamp() {
+	gl.QueryCounter(uint32(q), gl.TIMESTAMP)
+}
+
+// checks without waiting whether result of query is available
+func (q QueryObject) Available() bool {
+	var available uint32
+	gl.GetQueryObjectuiv(uint32(q), gl.QUERY_RESULT_AVAILABLE, &available)
+	return available != gl.FALSE
+}
+
+// waits for result of query, time elapsed or timestamp in nanoseconds
+func (q QueryObject) Result() (uint64, error) {
+	var result uint64
+	gl.GetQueryObjectui64v(uint32(q), gl.QUERY_RESULT, &result)
+	return result, GetError()
+}
+
+// waits for result of time elapsed query
+func (q QueryObject) Elapsed() (time.Duration, error) {
+	result, err := q.Result()
+	return time.Duration(result), err
 }
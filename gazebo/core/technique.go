@@ -1,6 +1,7 @@
 package core
 
 import "fmt"
+import "log"
 import "strings"
 import "github.com/go-gl/gl/v4.6-core/gl"
 
@@ -8,6 +9,25 @@ type FragmentShaderSource string
 type VertexShaderSource string
 type ComputeShaderSource string
 
+// shader compilation failure
+type CompileError struct {
+	Source interface{} // source of shader failed to compile
+	Log    string      // compiler's info log
+}
+
+func (e *CompileError) Error() string {
+	stage := "unknown"
+	switch e.Source.(type) {
+	case VertexShaderSource:
+		stage = "vertex"
+	case FragmentShaderSource:
+		stage = "fragment"
+	case ComputeShaderSource:
+		stage = "compute"
+	}
+	return fmt.Sprintf("failed to compile %v shader: %v\nsource:\n%v", stage, e.Log, e.Source)
+}
+
 // compile shader from GLSL text
 func compileShader(source interface{}) (uint32, error) {
 
@@ -43,13 +63,21 @@ func compileShader(source interface{}) (uint32, error) {
 		gl.GetShaderiv(shader, gl.INFO_LOG_LENGTH, &logLength)
 		log := strings.Repeat("\x00", int(logLength+1))
 		gl.GetShaderInfoLog(shader, logLength, nil, gl.Str(log))
-		return 0, fmt.Errorf("failed to compile %v: %v", source, log)
+		gl.DeleteShader(shader)
+		return 0, &CompileError{Source: source, Log: strings.TrimRight(log, "\x00")}
 	}
 
 	return shader, nil
 }
 
-type Technique uint32
+// linked program, its uniforms are read and set by name through accessors of float,
+// double, int (also samplers and images), uint and bool scalars, their vectors except
+// double ones, mat2, mat3 and mat4, and arrays of all of them except doubles;
+// other types, e.g. dvec*, dmat* and non-square matrices, are only kept on Replace
+type Technique struct {
+	program  uint32             // OpenGL program object
+	uniforms map[string]Uniform // active uniforms by name, cached at link time
+}
 
 func NewRenderTechnique(vertexShader *VertexShaderSource, fragmentShader *FragmentShaderSource) (*Technique, error) {
 	return newTechnique(vertexShader, fragmentShader, nil)
@@ -61,51 +89,61 @@ func NewComputeTechnique(computeShader *ComputeShaderSource) (*Technique, error)
 
 func newTechnique(vertexShader *VertexShaderSource, fragmentShader *FragmentShaderSource, computeShader *ComputeShaderSource) (*Technique, error) {
 
-	t := Technique(gl.CreateProgram())
+	t := Technique{program: gl.CreateProgram()}
+	track("Technique", t.program)
 
 	if vertexShader != nil {
 		vshader, err := compileShader(*vertexShader)
 		if err != nil {
-			return nil, fmt.Errorf("Failed to compile vertex shader: %v", err)
+			t.Delete()
+			return nil, err
 		}
-		gl.AttachShader(uint32(t), vshader)
+		gl.AttachShader(t.program, vshader)
 		gl.DeleteShader(vshader)
 	}
 
 	if fragmentShader != nil {
 		fshader, err := compileShader(*fragmentShader)
 		if err != nil {
-			return nil, fmt.Errorf("Failed to compile fragment shader: %v", err)
+			t.Delete()
+			return nil, err
 		}
-		gl.AttachShader(uint32(t), fshader)
+		gl.AttachShader(t.program, fshader)
 		gl.DeleteShader(fshader)
 	}
 
 	if computeShader != nil {
 		cshader, err := compileShader(*computeShader)
 		if err != nil {
-			return nil, fmt.Errorf("Failed to compile compute shader: %v", err)
+			t.Delete()
+			return nil, err
 		}
-		gl.AttachShader(uint32(t), cshader)
+		gl.AttachShader(t.program, cshader)
 		gl.DeleteShader(cshader)
 	}
 
 	if err := t.linkAndValidate(); err != nil {
+		t.Delete()
+		return nil, err
+	}
+	if err := t.cacheUniforms(); err != nil {
+		t.Delete()
 		return nil, err
 	}
-	CheckError()
 
 	return &t, nil
 }
 
 func (t *Technique) linkAndValidate() error {
-	p := uint32(*t)
+	p := t.program
 
 	var status int32
 	var logLength int32
 
 	gl.LinkProgram(p)
-	CheckError()
+	if err := GetError(); err != nil {
+		return err
+	}
 	gl.GetProgramiv(p, gl.LINK_STATUS, &status)
 	gl.GetProgramiv(p, gl.INFO_LOG_LENGTH, &logLength)
 	if logLength > 0 {
@@ -118,7 +156,9 @@ func (t *Technique) linkAndValidate() error {
 	}
 
 	gl.ValidateProgram(p)
-	CheckError()
+	if err := GetError(); err != nil {
+		return err
+	}
 	gl.GetProgramiv(p, gl.VALIDATE_STATUS, &status)
 	gl.GetProgramiv(p, gl.INFO_LOG_LENGTH, &logLength)
 	if logLength > 0 {
@@ -133,50 +173,47 @@ func (t *Technique) linkAndValidate() error {
 	return nil
 }
 
-func (t *Technique) GetUniformFloat32(name string) (value float32) {
-	uLocation := gl.GetUniformLocation(uint32(*t), gl.Str(fmt.Sprintf("%v\x00", name)))
-	gl.GetUniformfv(uint32(*t), uLocation, &value)
-	return
+// OpenGL program object of technique
+func (t *Technique) Program() uint32 {
+	return t.program
 }
 
-func (t *Technique) SetUniformFloat32(name string, value float32) {
-	uLocation := gl.GetUniformLocation(uint32(*t), gl.Str(fmt.Sprintf("%v\x00", name)))
-	disable := t.Enable()
-	defer disable()
-	gl.Uniform1f(uLocation, value)
-}
-
-func (t *Technique) GetUniformFloat64(name string) (value float64) {
-	uLocation := gl.GetUniformLocation(uint32(*t), gl.Str(fmt.Sprintf("%v\x00", name)))
-	gl.GetUniformdv(uint32(*t), uLocation, &value)
-	return
-}
-
-func (t *Technique) SetUniformFloat64(name string, value float64) {
-	uLocation := gl.GetUniformLocation(uint32(*t), gl.Str(fmt.Sprintf("%v\x00", name)))
-	disable := t.Enable()
-	defer disable()
-	gl.Uniform1d(uLocation, value)
-}
-
-func (t *Technique) GetUniformUint(name string) (value uint32) {
-	uLocation := gl.GetUniformLocation(uint32(*t), gl.Str(fmt.Sprintf("%v\x00", name)))
-	gl.GetUniformuiv(uint32(*t), uLocation, &value)
-	return
+// Replaces program of technique with program of `nt`, e.g. recompiled from changed
+// sources. Values of uniforms present in both programs are preserved. Old program
+// is deleted, `nt` must not be used afterwards. Errors are returned only before the
+// swap, e.g. an error pending from earlier calls, then technique keeps its program
+// and `nt` is intact; failure to delete the old program is logged.
+func (t *Technique) Replace(nt *Technique) error {
+	if err := GetError(); err != nil {
+		return err
+	}
+	if err := copyUniforms(nt, t); err != nil {
+		return err
+	}
+	old := t.program
+	t.program, t.uniforms = nt.program, nt.uniforms
+	nt.program, nt.uniforms = 0, nil
+	(&Technique{program: old}).Delete()
+	if err := GetError(); err != nil {
+		log.Printf("Failed to delete replaced program %v: %v\n", old, err)
+	}
+	return nil
 }
 
-func (t *Technique) SetUniformUint(name string, value uint32) {
-	uLocation := gl.GetUniformLocation(uint32(*t), gl.Str(fmt.Sprintf("%v\x00", name)))
-	disable := t.Enable()
-	defer disable()
-	gl.Uniform1ui(uLocation, value)
+// deletes program of technique, technique must not be used afterwards
+func (t *Technique) Delete() {
+	untrack("Technique", t.program)
+	gl.DeleteProgram(t.program)
+	t.program, t.uniforms = 0, nil
 }
 
-func (t *Technique) Enable() func() {
-	gl.UseProgram(uint32(*t))
-	CheckError()
+// makes technique current, returned function restores default program
+func (t *Technique) Enable() (func(), error) {
+	gl.UseProgram(t.program)
+	if err := GetError(); err != nil {
+		return nil, err
+	}
 	return func() {
 		gl.UseProgram(0)
-		CheckError()
-	}
+	}, nil
 }
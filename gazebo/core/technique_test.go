@@ -0,0 +1,39 @@
+package core
+
+import "errors"
+import "testing"
+import "github.com/go-gl/gl/v4.6-core/gl"
+
+func TestReplace(t *testing.T) {
+	withContext(t)
+	tech := newUniformsTechnique(t)
+	src := ComputeShaderSource(UNIFORMS_SHADER)
+	nt, err := NewComputeTechnique(&src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(nt.Delete)
+	old, replacement := tech.Program(), nt.Program()
+
+	// pending error fails replacing before the swap
+	gl.Enable(0xFFFF)
+	if err := tech.Replace(nt); !errors.Is(err, ErrInvalidEnum) {
+		t.Fatalf("got %v, want pending GL_INVALID_ENUM", err)
+	}
+	if tech.Program() != old || nt.Program() != replacement || !gl.IsProgram(old) {
+		t.Fatalf("failed replace changed programs: technique %v, new %v", tech.Program(), nt.Program())
+	}
+	if err := tech.SetUniformBVec2("b2", BVec2{Y: true}); err != nil {
+		t.Fatalf("technique isn't usable after failed replace: %v", err)
+	}
+
+	if err := tech.Replace(nt); err != nil {
+		t.Fatal(err)
+	}
+	if tech.Program() != replacement || nt.Program() != 0 || gl.IsProgram(old) {
+		t.Errorf("technique runs %v, new technique has %v, old program exists %v", tech.Program(), nt.Program(), gl.IsProgram(old))
+	}
+	if v, err := tech.GetUniformBVec2("b2"); err != nil || v != (BVec2{Y: true}) {
+		t.Errorf("got %v, %v after replace", v, err)
+	}
+}
@@ -0,0 +1,85 @@
+package core
+
+import "errors"
+import "fmt"
+import "runtime"
+import "sort"
+import "strings"
+
+var ErrLeakedObjects = errors.New("leaked OpenGL objects")
+
+// OpenGL object created while tracking is on and not deleted yet
+type LiveObject struct {
+	Kind string // type of object, e.g. "VertexBufferObject"
+	ID   uint32 // OpenGL name of object
+	Site string // function, file and line outside of core where object was created
+}
+
+type objectKey struct {
+	kind string
+	id   uint32
+}
+
+// live objects by kind and id, nil when tracking is off
+var liveObjects map[objectKey]LiveObject
+
+// starts tracking objects created and deleted afterwards, objects
+// created before are not reported even if they are never deleted
+func EnableObjectTracking() {
+	if liveObjects == nil {
+		liveObjects = make(map[objectKey]LiveObject)
+	}
+}
+
+// the first caller outside of core package
+func creationSite() string {
+	pcs := make([]uintptr, 16)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "github.com/dmarychev/gazebo/core.") || !more {
+			return fmt.Sprintf("%v (%v:%v)", frame.Function, frame.File, frame.Line)
+		}
+	}
+}
+
+func track(kind string, id uint32) {
+	if liveObjects != nil && id != 0 {
+		liveObjects[objectKey{kind, id}] = LiveObject{Kind: kind, ID: id, Site: creationSite()}
+	}
+}
+
+func untrack(kind string, id uint32) {
+	if liveObjects != nil {
+		delete(liveObjects, objectKey{kind, id})
+	}
+}
+
+// objects created since tracking is on and not deleted, ordered by kind and id
+func LiveObjects() []LiveObject {
+	objects := make([]LiveObject, 0, len(liveObjects))
+	for _, o := range liveObjects {
+		objects = append(objects, o)
+	}
+	sort.Slice(objects, func(i, j int) bool {
+		if objects[i].Kind != objects[j].Kind {
+			return objects[i].Kind < objects[j].Kind
+		}
+		return objects[i].ID < objects[j].ID
+	})
+	return objects
+}
+
+// returns error wrapping ErrLeakedObjects listing live objects, e.g. at shutdown
+// after everything is supposed to be deleted, nil if there are none
+func CheckLeaks() error {
+	objects := LiveObjects()
+	if len(objects) == 0 {
+		return nil
+	}
+	lines := make([]string, len(objects))
+	for i, o := range objects {
+		lines[i] = fmt.Sprintf("%v %v created at %v", o.Kind, o.ID, o.Site)
+	}
+	return fmt.Errorf("%w: %v\n - %v", ErrLeakedObjects, len(objects), strings.Join(lines, "\n - "))
+}
@@ -0,0 +1,669 @@
+package core
+
+import "errors"
+import "fmt"
+import "sort"
+import "strings"
+import "github.com/go-gl/gl/v4.6-core/gl"
+
+var (
+	ErrUnknownUniform   = errors.New("unknown uniform")
+	ErrUniformType      = errors.New("wrong uniform type")
+	ErrUniformArraySize = errors.New("uniform array is too small")
+)
+
+// active uniform variable of linked technique
+type Uniform struct {
+	Name     string // name without array suffix
+	Location int32  // location of the first element
+	Type     uint32 // GLSL type, e.g. gl.FLOAT_VEC2
+	Size     int32  // number of array elements, 1 for non-arrays
+}
+
+// uniforms of these types are set as int, including samplers and images
+var intTypes = []uint32{
+	gl.INT,
+	gl.SAMPLER_1D, gl.SAMPLER_2D, gl.SAMPLER_3D, gl.SAMPLER_CUBE, gl.SAMPLER_2D_SHADOW,
+	gl.SAMPLER_2D_ARRAY, gl.SAMPLER_2D_MULTISAMPLE, gl.SAMPLER_BUFFER,
+	gl.INT_SAMPLER_2D, gl.UNSIGNED_INT_SAMPLER_2D, gl.IMAGE_2D, gl.IMAGE_BUFFER,
+}
+
+// enumerates active uniforms of linked program
+func activeUniforms(program uint32) ([]Uniform, error) {
+	var numUniforms int32
+	gl.GetProgramInterfaceiv(program, gl.UNIFORM, gl.ACTIVE_RESOURCES, &numUniforms)
+	if err := GetError(); err != nil {
+		return nil, err
+	}
+
+	uniforms := make([]Uniform, 0, numUniforms)
+	for uniformIndex := uint32(0); uniformIndex < uint32(numUniforms); uniformIndex++ {
+
+		// retrieve name length, location, type and array size at once
+		props := []uint32{gl.NAME_LENGTH, gl.LOCATION, gl.TYPE, gl.ARRAY_SIZE, gl.BLOCK_INDEX}
+		params := make([]int32, len(props))
+		gl.GetProgramResourceiv(program, gl.UNIFORM, uniformIndex, int32(len(props)), &props[0], int32(len(params)), nil, &params[0])
+		if err := GetError(); err != nil {
+			return nil, err
+		}
+
+		// skip members of uniform blocks, they have no locations
+		if params[4] != -1 {
+			continue
+		}
+
+		// retrieve name
+		nameLen := params[0]
+		name := make([]uint8, nameLen)
+		gl.GetProgramResourceName(program, gl.UNIFORM, uniformIndex, int32(len(name)), &nameLen, &name[0])
+		if err := GetError(); err != nil {
+			return nil, err
+		}
+
+		uniforms = append(uniforms, Uniform{
+			Name:     strings.TrimSuffix(string(name[:nameLen]), "[0]"),
+			Location: params[1],
+			Type:     uint32(params[2]),
+			Size:     params[3],
+		})
+	}
+
+	return uniforms, nil
+}
+
+func (t *Technique) cacheUniforms() error {
+	uniforms, err := activeUniforms(t.program)
+	if err != nil {
+		return err
+	}
+	t.uniforms = make(map[string]Uniform, len(uniforms))
+	for _, u := range uniforms {
+		t.uniforms[u.Name] = u
+	}
+	return nil
+}
+
+// active uniforms of technique
+func (t *Technique) Uniforms() []Uniform {
+	uniforms := make([]Uniform, 0, len(t.uniforms))
+	for _, u := range t.uniforms {
+		uniforms = append(uniforms, u)
+	}
+	sort.Slice(uniforms, func(i, j int) bool { return uniforms[i].Location < uniforms[j].Location })
+	return uniforms
+}
+
+// looks up cached uniform and checks it has one of given types and at least `count` elements
+func (t *Technique) uniform(name string, count int, types ...uint32) (Uniform, error) {
+	u, ok := t.uniforms[name]
+	if !ok {
+		return u, fmt.Errorf("%w %q in technique %v", ErrUnknownUniform, name, t.program)
+	}
+	if int(u.Size) < count {
+		return u, fmt.Errorf("%w: %q has %v elements, got %v", ErrUniformArraySize, name, u.Size, count)
+	}
+	for _, ty := range types {
+		if u.Type == ty {
+			return u, nil
+		}
+	}
+	return u, fmt.Errorf("%w: %q has type 0x%x", ErrUniformType, name, u.Type)
+}
+
+// calls `set` with location of uniform while technique is enabled
+func (t *Technique) setUniform(name string, count int, set func(location int32), types ...uint32) error {
+	u, err := t.uniform(name, count, types...)
+	if err != nil {
+		return err
+	}
+	disable, err := t.Enable()
+	if err != nil {
+		return err
+	}
+	defer disable()
+	set(u.Location)
+	return GetError()
+}
+
+// calls `get` with program and location of uniform
+func (t *Technique) getUniform(name string, get func(program uint32, location int32), types ...uint32) error {
+	u, err := t.uniform(name, 1, types...)
+	if err != nil {
+		return err
+	}
+	get(t.program, u.Location)
+	return GetError()
+}
+
+// calls `get` with program and location of every element of uniform array
+func (t *Technique) getUniformArray(name string, count int, get func(program uint32, location int32, i int), types ...uint32) error {
+	u, err := t.uniform(name, count, types...)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < count; i++ {
+		get(t.program, u.Location+int32(i), i)
+	}
+	return GetError()
+}
+
+// Scalars
+
+func (t *Technique) GetUniformFloat32(name string) (value float32, err error) {
+	err = t.getUniform(name, func(p uint32, l int32) { gl.GetUniformfv(p, l, &value) }, gl.FLOAT)
+	return
+}
+
+func (t *Technique) SetUniformFloat32(name string, value float32) error {
+	return t.setUniform(name, 1, func(l int32) { gl.Uniform1f(l, value) }, gl.FLOAT)
+}
+
+func (t *Technique) GetUniformFloat64(name string) (value float64, err error) {
+	err = t.getUniform(name, func(p uint32, l int32) { gl.GetUniformdv(p, l, &value) }, gl.DOUBLE)
+	return
+}
+
+func (t *Technique) SetUniformFloat64(name string, value float64) error {
+	return t.setUniform(name, 1, func(l int32) { gl.Uniform1d(l, value) }, gl.DOUBLE)
+}
+
+func (t *Technique) GetUniformUint(name string) (value uint32, err error) {
+	err = t.getUniform(name, func(p uint32, l int32) { gl.GetUniformuiv(p, l, &value) }, gl.UNSIGNED_INT)
+	return
+}
+
+func (t *Technique) SetUniformUint(name string, value uint32) error {
+	return t.setUniform(name, 1, func(l int32) { gl.Uniform1ui(l, value) }, gl.UNSIGNED_INT)
+}
+
+func (t *Technique) GetUniformInt(name string) (value int32, err error) {
+	err = t.getUniform(name, func(p uint32, l int32) { gl.GetUniformiv(p, l, &value) }, intTypes...)
+	return
+}
+
+func (t *Technique) SetUniformInt(name string, value int32) error {
+	return t.setUniform(name, 1, func(l int32) { gl.Uniform1i(l, value) }, intTypes...)
+}
+
+func (t *Technique) GetUniformBool(name string) (value bool, err error) {
+	var v int32
+	err = t.getUniform(name, func(p uint32, l int32) { gl.GetUniformiv(p, l, &v) }, gl.BOOL)
+	return v != 0, err
+}
+
+func (t *Technique) SetUniformBool(name string, value bool) error {
+	v := boolInt(value)
+	return t.setUniform(name, 1, func(l int32) { gl.Uniform1i(l, v) }, gl.BOOL)
+}
+
+// Vectors
+
+func (t *Technique) GetUniformVec2(name string) (value Vec2, err error) {
+	err = t.getUniform(name, func(p uint32, l int32) { gl.GetUniformfv(p, l, &value.X) }, gl.FLOAT_VEC2)
+	return
+}
+
+func (t *Technique) SetUniformVec2(name string, value Vec2) error {
+	return t.setUniform(name, 1, func(l int32) { gl.Uniform2fv(l, 1, &value.X) }, gl.FLOAT_VEC2)
+}
+
+func (t *Technique) GetUniformVec3(name string) (value Vec3, err error) {
+	err = t.getUniform(name, func(p uint32, l int32) { gl.GetUniformfv(p, l, &value.X) }, gl.FLOAT_VEC3)
+	return
+}
+
+func (t *Technique) SetUniformVec3(name string, value Vec3) error {
+	return t.setUniform(name, 1, func(l int32) { gl.Uniform3fv(l, 1, &value.X) }, gl.FLOAT_VEC3)
+}
+
+func (t *Technique) GetUniformVec4(name string) (value Vec4, err error) {
+	err = t.getUniform(name, func(p uint32, l int32) { gl.GetUniformfv(p, l, &value.X) }, gl.FLOAT_VEC4)
+	return
+}
+
+func (t *Technique) SetUniformVec4(name string, value Vec4) error {
+	return t.setUniform(name, 1, func(l int32) { gl.Uniform4fv(l, 1, &value.X) }, gl.FLOAT_VEC4)
+}
+
+func (t *Technique) GetUniformIVec2(name string) (value IVec2, err error) {
+	err = t.getUniform(name, func(p uint32, l int32) { gl.GetUniformiv(p, l, &value.X) }, gl.INT_VEC2)
+	return
+}
+
+func (t *Technique) SetUniformIVec2(name string, value IVec2) error {
+	return t.setUniform(name, 1, func(l int32) { gl.Uniform2iv(l, 1, &value.X) }, gl.INT_VEC2)
+}
+
+func (t *Technique) GetUniformIVec3(name string) (value IVec3, err error) {
+	err = t.getUniform(name, func(p uint32, l int32) { gl.GetUniformiv(p, l, &value.X) }, gl.INT_VEC3)
+	return
+}
+
+func (t *Technique) SetUniformIVec3(name string, value IVec3) error {
+	return t.setUniform(name, 1, func(l int32) { gl.Uniform3iv(l, 1, &value.X) }, gl.INT_VEC3)
+}
+
+func (t *Technique) GetUniformIVec4(name string) (value IVec4, err error) {
+	err = t.getUniform(name, func(p uint32, l int32) { gl.GetUniformiv(p, l, &value.X) }, gl.INT_VEC4)
+	return
+}
+
+func (t *Technique) SetUniformIVec4(name string, value IVec4) error {
+	return t.setUniform(name, 1, func(l int32) { gl.Uniform4iv(l, 1, &value.X) }, gl.INT_VEC4)
+}
+
+func (t *Technique) GetUniformUVec2(name string) (value UVec2, err error) {
+	err = t.getUniform(name, func(p uint32, l int32) { gl.GetUniformuiv(p, l, &value.X) }, gl.UNSIGNED_INT_VEC2)
+	return
+}
+
+func (t *Technique) SetUniformUVec2(name string, value UVec2) error {
+	return t.setUniform(name, 1, func(l int32) { gl.Uniform2uiv(l, 1, &value.X) }, gl.UNSIGNED_INT_VEC2)
+}
+
+func (t *Technique) GetUniformUVec3(name string) (value UVec3, err error) {
+	err = t.getUniform(name, func(p uint32, l int32) { gl.GetUniformuiv(p, l, &value.X) }, gl.UNSIGNED_INT_VEC3)
+	return
+}
+
+func (t *Technique) SetUniformUVec3(name string, value UVec3) error {
+	return t.setUniform(name, 1, func(l int32) { gl.Uniform3uiv(l, 1, &value.X) }, gl.UNSIGNED_INT_VEC3)
+}
+
+func (t *Technique) GetUniformUVec4(name string) (value UVec4, err error) {
+	err = t.getUniform(name, func(p uint32, l int32) { gl.GetUniformuiv(p, l, &value.X) }, gl.UNSIGNED_INT_VEC4)
+	return
+}
+
+func (t *Technique) SetUniformUVec4(name string, value UVec4) error {
+	return t.setUniform(name, 1, func(l int32) { gl.Uniform4uiv(l, 1, &value.X) }, gl.UNSIGNED_INT_VEC4)
+}
+
+func (t *Technique) GetUniformBVec2(name string) (value BVec2, err error) {
+	var v [2]int32
+	err = t.getUniform(name, func(p uint32, l int32) { gl.GetUniformiv(p, l, &v[0]) }, gl.BOOL_VEC2)
+	return BVec2{X: v[0] != 0, Y: v[1] != 0}, err
+}
+
+func (t *Technique) SetUniformBVec2(name string, value BVec2) error {
+	v := []int32{boolInt(value.X), boolInt(value.Y)}
+	return t.setUniform(name, 1, func(l int32) { gl.Uniform2iv(l, 1, &v[0]) }, gl.BOOL_VEC2)
+}
+
+func (t *Technique) GetUniformBVec3(name string) (value BVec3, err error) {
+	var v [3]int32
+	err = t.getUniform(name, func(p uint32, l int32) { gl.GetUniformiv(p, l, &v[0]) }, gl.BOOL_VEC3)
+	return BVec3{X: v[0] != 0, Y: v[1] != 0, Z: v[2] != 0}, err
+}
+
+func (t *Technique) SetUniformBVec3(name string, value BVec3) error {
+	v := []int32{boolInt(value.X), boolInt(value.Y), boolInt(value.Z)}
+	return t.setUniform(name, 1, func(l int32) { gl.Uniform3iv(l, 1, &v[0]) }, gl.BOOL_VEC3)
+}
+
+func (t *Technique) GetUniformBVec4(name string) (value BVec4, err error) {
+	var v [4]int32
+	err = t.getUniform(name, func(p uint32, l int32) { gl.GetUniformiv(p, l, &v[0]) }, gl.BOOL_VEC4)
+	return BVec4{X: v[0] != 0, Y: v[1] != 0, Z: v[2] != 0, W: v[3] != 0}, err
+}
+
+func (t *Technique) SetUniformBVec4(name string, value BVec4) error {
+	v := []int32{boolInt(value.X), boolInt(value.Y), boolInt(value.Z), boolInt(value.W)}
+	return t.setUniform(name, 1, func(l int32) { gl.Uniform4iv(l, 1, &v[0]) }, gl.BOOL_VEC4)
+}
+
+// Matrices
+
+func (t *Technique) GetUniformMat2(name string) (value Mat2, err error) {
+	err = t.getUniform(name, func(p uint32, l int32) { gl.GetUniformfv(p, l, &value[0]) }, gl.FLOAT_MAT2)
+	return
+}
+
+func (t *Technique) SetUniformMat2(name string, value Mat2) error {
+	return t.setUniform(name, 1, func(l int32) { gl.UniformMatrix2fv(l, 1, false, &value[0]) }, gl.FLOAT_MAT2)
+}
+
+func (t *Technique) GetUniformMat3(name string) (value Mat3, err error) {
+	err = t.getUniform(name, func(p uint32, l int32) { gl.GetUniformfv(p, l, &value[0]) }, gl.FLOAT_MAT3)
+	return
+}
+
+func (t *Technique) SetUniformMat3(name string, value Mat3) error {
+	return t.setUniform(name, 1, func(l int32) { gl.UniformMatrix3fv(l, 1, false, &value[0]) }, gl.FLOAT_MAT3)
+}
+
+func (t *Technique) GetUniformMat4(name string) (value Mat4, err error) {
+	err = t.getUniform(name, func(p uint32, l int32) { gl.GetUniformfv(p, l, &value[0]) }, gl.FLOAT_MAT4)
+	return
+}
+
+func (t *Technique) SetUniformMat4(name string, value Mat4) error {
+	return t.setUniform(name, 1, func(l int32) { gl.UniformMatrix4fv(l, 1, false, &value[0]) }, gl.FLOAT_MAT4)
+}
+
+// Arrays, getters fill all elements of `values`
+
+func (t *Technique) GetUniformFloat32Array(name string, values []float32) error {
+	return t.getUniformArray(name, len(values), func(p uint32, l int32, i int) { gl.GetUniformfv(p, l, &values[i]) }, gl.FLOAT)
+}
+
+func (t *Technique) SetUniformFloat32Array(name string, values []float32) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return t.setUniform(name, len(values), func(l int32) { gl.Uniform1fv(l, int32(len(values)), &values[0]) }, gl.FLOAT)
+}
+
+func (t *Technique) GetUniformIntArray(name string, values []int32) error {
+	return t.getUniformArray(name, len(values), func(p uint32, l int32, i int) { gl.GetUniformiv(p, l, &values[i]) }, intTypes...)
+}
+
+func (t *Technique) SetUniformIntArray(name string, values []int32) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return t.setUniform(name, len(values), func(l int32) { gl.Uniform1iv(l, int32(len(values)), &values[0]) }, intTypes...)
+}
+
+func (t *Technique) GetUniformUintArray(name string, values []uint32) error {
+	return t.getUniformArray(name, len(values), func(p uint32, l int32, i int) { gl.GetUniformuiv(p, l, &values[i]) }, gl.UNSIGNED_INT)
+}
+
+func (t *Technique) SetUniformUintArray(name string, values []uint32) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return t.setUniform(name, len(values), func(l int32) { gl.Uniform1uiv(l, int32(len(values)), &values[0]) }, gl.UNSIGNED_INT)
+}
+
+func (t *Technique) GetUniformVec2Array(name string, values []Vec2) error {
+	return t.getUniformArray(name, len(values), func(p uint32, l int32, i int) { gl.GetUniformfv(p, l, &values[i].X) }, gl.FLOAT_VEC2)
+}
+
+func (t *Technique) SetUniformVec2Array(name string, values []Vec2) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return t.setUniform(name, len(values), func(l int32) { gl.Uniform2fv(l, int32(len(values)), &values[0].X) }, gl.FLOAT_VEC2)
+}
+
+func (t *Technique) GetUniformVec3Array(name string, values []Vec3) error {
+	return t.getUniformArray(name, len(values), func(p uint32, l int32, i int) { gl.GetUniformfv(p, l, &values[i].X) }, gl.FLOAT_VEC3)
+}
+
+func (t *Technique) SetUniformVec3Array(name string, values []Vec3) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return t.setUniform(name, len(values), func(l int32) { gl.Uniform3fv(l, int32(len(values)), &values[0].X) }, gl.FLOAT_VEC3)
+}
+
+func (t *Technique) GetUniformVec4Array(name string, values []Vec4) error {
+	return t.getUniformArray(name, len(values), func(p uint32, l int32, i int) { gl.GetUniformfv(p, l, &values[i].X) }, gl.FLOAT_VEC4)
+}
+
+func (t *Technique) SetUniformVec4Array(name string, values []Vec4) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return t.setUniform(name, len(values), func(l int32) { gl.Uniform4fv(l, int32(len(values)), &values[0].X) }, gl.FLOAT_VEC4)
+}
+
+func (t *Technique) GetUniformMat2Array(name string, values []Mat2) error {
+	return t.getUniformArray(name, len(values), func(p uint32, l int32, i int) { gl.GetUniformfv(p, l, &values[i][0]) }, gl.FLOAT_MAT2)
+}
+
+func (t *Technique) SetUniformMat2Array(name string, values []Mat2) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return t.setUniform(name, len(values), func(l int32) { gl.UniformMatrix2fv(l, int32(len(values)), false, &values[0][0]) }, gl.FLOAT_MAT2)
+}
+
+func (t *Technique) GetUniformMat3Array(name string, values []Mat3) error {
+	return t.getUniformArray(name, len(values), func(p uint32, l int32, i int) { gl.GetUniformfv(p, l, &values[i][0]) }, gl.FLOAT_MAT3)
+}
+
+func (t *Technique) SetUniformMat3Array(name string, values []Mat3) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return t.setUniform(name, len(values), func(l int32) { gl.UniformMatrix3fv(l, int32(len(values)), false, &values[0][0]) }, gl.FLOAT_MAT3)
+}
+
+func (t *Technique) GetUniformMat4Array(name string, values []Mat4) error {
+	return t.getUniformArray(name, len(values), func(p uint32, l int32, i int) { gl.GetUniformfv(p, l, &values[i][0]) }, gl.FLOAT_MAT4)
+}
+
+func (t *Technique) SetUniformMat4Array(name string, values []Mat4) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return t.setUniform(name, len(values), func(l int32) { gl.UniformMatrix4fv(l, int32(len(values)), false, &values[0][0]) }, gl.FLOAT_MAT4)
+}
+
+func (t *Technique) GetUniformIVec2Array(name string, values []IVec2) error {
+	return t.getUniformArray(name, len(values), func(p uint32, l int32, i int) { gl.GetUniformiv(p, l, &values[i].X) }, gl.INT_VEC2)
+}
+
+func (t *Technique) SetUniformIVec2Array(name string, values []IVec2) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return t.setUniform(name, len(values), func(l int32) { gl.Uniform2iv(l, int32(len(values)), &values[0].X) }, gl.INT_VEC2)
+}
+
+func (t *Technique) GetUniformIVec3Array(name string, values []IVec3) error {
+	return t.getUniformArray(name, len(values), func(p uint32, l int32, i int) { gl.GetUniformiv(p, l, &values[i].X) }, gl.INT_VEC3)
+}
+
+func (t *Technique) SetUniformIVec3Array(name string, values []IVec3) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return t.setUniform(name, len(values), func(l int32) { gl.Uniform3iv(l, int32(len(values)), &values[0].X) }, gl.INT_VEC3)
+}
+
+func (t *Technique) GetUniformIVec4Array(name string, values []IVec4) error {
+	return t.getUniformArray(name, len(values), func(p uint32, l int32, i int) { gl.GetUniformiv(p, l, &values[i].X) }, gl.INT_VEC4)
+}
+
+func (t *Technique) SetUniformIVec4Array(name string, values []IVec4) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return t.setUniform(name, len(values), func(l int32) { gl.Uniform4iv(l, int32(len(values)), &values[0].X) }, gl.INT_VEC4)
+}
+
+func (t *Technique) GetUniformUVec2Array(name string, values []UVec2) error {
+	return t.getUniformArray(name, len(values), func(p uint32, l int32, i int) { gl.GetUniformuiv(p, l, &values[i].X) }, gl.UNSIGNED_INT_VEC2)
+}
+
+func (t *Technique) SetUniformUVec2Array(name string, values []UVec2) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return t.setUniform(name, len(values), func(l int32) { gl.Uniform2uiv(l, int32(len(values)), &values[0].X) }, gl.UNSIGNED_INT_VEC2)
+}
+
+func (t *Technique) GetUniformUVec3Array(name string, values []UVec3) error {
+	return t.getUniformArray(name, len(values), func(p uint32, l int32, i int) { gl.GetUniformuiv(p, l, &values[i].X) }, gl.UNSIGNED_INT_VEC3)
+}
+
+func (t *Technique) SetUniformUVec3Array(name string, values []UVec3) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return t.setUniform(name, len(values), func(l int32) { gl.Uniform3uiv(l, int32(len(values)), &values[0].X) }, gl.UNSIGNED_INT_VEC3)
+}
+
+func (t *Technique) GetUniformUVec4Array(name string, values []UVec4) error {
+	return t.getUniformArray(name, len(values), func(p uint32, l int32, i int) { gl.GetUniformuiv(p, l, &values[i].X) }, gl.UNSIGNED_INT_VEC4)
+}
+
+func (t *Technique) SetUniformUVec4Array(name string, values []UVec4) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return t.setUniform(name, len(values), func(l int32) { gl.Uniform4uiv(l, int32(len(values)), &values[0].X) }, gl.UNSIGNED_INT_VEC4)
+}
+
+func (t *Technique) GetUniformBoolArray(name string, values []bool) error {
+	return t.getUniformArray(name, len(values), func(p uint32, l int32, i int) {
+		var v int32
+		gl.GetUniformiv(p, l, &v)
+		values[i] = v != 0
+	}, gl.BOOL)
+}
+
+func (t *Technique) SetUniformBoolArray(name string, values []bool) error {
+	if len(values) == 0 {
+		return nil
+	}
+	v := make([]int32, len(values))
+	for i, b := range values {
+		v[i] = boolInt(b)
+	}
+	return t.setUniform(name, len(values), func(l int32) { gl.Uniform1iv(l, int32(len(v)), &v[0]) }, gl.BOOL)
+}
+
+func (t *Technique) GetUniformBVec2Array(name string, values []BVec2) error {
+	return t.getUniformArray(name, len(values), func(p uint32, l int32, i int) {
+		var v [2]int32
+		gl.GetUniformiv(p, l, &v[0])
+		values[i] = BVec2{X: v[0] != 0, Y: v[1] != 0}
+	}, gl.BOOL_VEC2)
+}
+
+func (t *Technique) SetUniformBVec2Array(name string, values []BVec2) error {
+	if len(values) == 0 {
+		return nil
+	}
+	v := make([]int32, 0, 2*len(values))
+	for _, b := range values {
+		v = append(v, boolInt(b.X), boolInt(b.Y))
+	}
+	return t.setUniform(name, len(values), func(l int32) { gl.Uniform2iv(l, int32(len(values)), &v[0]) }, gl.BOOL_VEC2)
+}
+
+func (t *Technique) GetUniformBVec3Array(name string, values []BVec3) error {
+	return t.getUniformArray(name, len(values), func(p uint32, l int32, i int) {
+		var v [3]int32
+		gl.GetUniformiv(p, l, &v[0])
+		values[i] = BVec3{X: v[0] != 0, Y: v[1] != 0, Z: v[2] != 0}
+	}, gl.BOOL_VEC3)
+}
+
+func (t *Technique) SetUniformBVec3Array(name string, values []BVec3) error {
+	if len(values) == 0 {
+		return nil
+	}
+	v := make([]int32, 0, 3*len(values))
+	for _, b := range values {
+		v = append(v, boolInt(b.X), boolInt(b.Y), boolInt(b.Z))
+	}
+	return t.setUniform(name, len(values), func(l int32) { gl.Uniform3iv(l, int32(len(values)), &v[0]) }, gl.BOOL_VEC3)
+}
+
+func (t *Technique) GetUniformBVec4Array(name string, values []BVec4) error {
+	return t.getUniformArray(name, len(values), func(p uint32, l int32, i int) {
+		var v [4]int32
+		gl.GetUniformiv(p, l, &v[0])
+		values[i] = BVec4{X: v[0] != 0, Y: v[1] != 0, Z: v[2] != 0, W: v[3] != 0}
+	}, gl.BOOL_VEC4)
+}
+
+func (t *Technique) SetUniformBVec4Array(name string, values []BVec4) error {
+	if len(values) == 0 {
+		return nil
+	}
+	v := make([]int32, 0, 4*len(values))
+	for _, b := range values {
+		v = append(v, boolInt(b.X), boolInt(b.Y), boolInt(b.Z), boolInt(b.W))
+	}
+	return t.setUniform(name, len(values), func(l int32) { gl.Uniform4iv(l, int32(len(values)), &v[0]) }, gl.BOOL_VEC4)
+}
+
+func boolInt(b bool) int32 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// Copying between programs
+
+func matrixSetter(set func(program uint32, location int32, count int32, transpose bool, value *float32)) func(uint32, int32, int32, *float32) {
+	return func(p uint32, l int32, count int32, value *float32) { set(p, l, count, false, value) }
+}
+
+// setters of single uniform value by GLSL type, grouped by type of components
+var (
+	floatSetters = map[uint32]func(program uint32, location int32, count int32, value *float32){
+		gl.FLOAT:      gl.ProgramUniform1fv,
+		gl.FLOAT_VEC2: gl.ProgramUniform2fv,
+		gl.FLOAT_VEC3: gl.ProgramUniform3fv,
+		gl.FLOAT_VEC4: gl.ProgramUniform4fv,
+		gl.FLOAT_MAT2: matrixSetter(gl.ProgramUniformMatrix2fv),
+		gl.FLOAT_MAT3: matrixSetter(gl.ProgramUniformMatrix3fv),
+		gl.FLOAT_MAT4: matrixSetter(gl.ProgramUniformMatrix4fv),
+	}
+	doubleSetters = map[uint32]func(program uint32, location int32, count int32, value *float64){
+		gl.DOUBLE:      gl.ProgramUniform1dv,
+		gl.DOUBLE_VEC2: gl.ProgramUniform2dv,
+		gl.DOUBLE_VEC3: gl.ProgramUniform3dv,
+		gl.DOUBLE_VEC4: gl.ProgramUniform4dv,
+	}
+	intSetters = map[uint32]func(program uint32, location int32, count int32, value *int32){
+		gl.BOOL:      gl.ProgramUniform1iv,
+		gl.BOOL_VEC2: gl.ProgramUniform2iv,
+		gl.BOOL_VEC3: gl.ProgramUniform3iv,
+		gl.BOOL_VEC4: gl.ProgramUniform4iv,
+		gl.INT_VEC2:  gl.ProgramUniform2iv,
+		gl.INT_VEC3:  gl.ProgramUniform3iv,
+		gl.INT_VEC4:  gl.ProgramUniform4iv,
+	}
+	uintSetters = map[uint32]func(program uint32, location int32, count int32, value *uint32){
+		gl.UNSIGNED_INT:      gl.ProgramUniform1uiv,
+		gl.UNSIGNED_INT_VEC2: gl.ProgramUniform2uiv,
+		gl.UNSIGNED_INT_VEC3: gl.ProgramUniform3uiv,
+		gl.UNSIGNED_INT_VEC4: gl.ProgramUniform4uiv,
+	}
+)
+
+func init() {
+	for _, ty := range intTypes {
+		intSetters[ty] = gl.ProgramUniform1iv
+	}
+}
+
+// copies values of `src` uniforms to `dst` uniforms having the same name, type and size
+func copyUniforms(dst, src *Technique) error {
+	for name, su := range src.uniforms {
+		du, ok := dst.uniforms[name]
+		if !ok || du.Type != su.Type || du.Size != su.Size {
+			continue
+		}
+		for i := int32(0); i < su.Size; i++ {
+			dl, sl := du.Location+i, su.Location+i
+			if set, ok := floatSetters[su.Type]; ok {
+				var value [16]float32
+				gl.GetUniformfv(src.program, sl, &value[0])
+				set(dst.program, dl, 1, &value[0])
+			} else if set, ok := doubleSetters[su.Type]; ok {
+				var value [4]float64
+				gl.GetUniformdv(src.program, sl, &value[0])
+				set(dst.program, dl, 1, &value[0])
+			} else if set, ok := intSetters[su.Type]; ok {
+				var value [4]int32
+				gl.GetUniformiv(src.program, sl, &value[0])
+				set(dst.program, dl, 1, &value[0])
+			} else if set, ok := uintSetters[su.Type]; ok {
+				var value [4]uint32
+				gl.GetUniformuiv(src.program, sl, &value[0])
+				set(dst.program, dl, 1, &value[0])
+			}
+		}
+	}
+	return GetError()
+}
@@ -0,0 +1,154 @@
+package core
+
+import "errors"
+import "testing"
+
+const UNIFORMS_SHADER = `#version 460
+layout(local_size_x = 1) in;
+layout(std430, binding = 0) buffer Out { float result; };
+uniform bool b[2];
+uniform bvec2 b2;
+uniform bvec3 b3[2];
+uniform bvec4 b4[2];
+uniform ivec2 i2[2];
+uniform ivec3 i3[2];
+uniform ivec4 i4[2];
+uniform uvec2 u2[2];
+uniform uvec3 u3[2];
+uniform uvec4 u4[2];
+uniform mat2 m2[2];
+uniform mat3 m3[2];
+void main() {
+    float s = float(b[1]) + float(b2.y) + float(b3[1].z) + float(b4[1].w);
+    s += float(i2[1].y + i3[1].z + i4[1].w) + float(u2[1].y + u3[1].z + u4[1].w);
+    s += m2[1][1][1] + m3[1][2][2];
+    result = s;
+}
+`
+
+func newUniformsTechnique(t *testing.T) *Technique {
+	t.Helper()
+	src := ComputeShaderSource(UNIFORMS_SHADER)
+	tech, err := NewComputeTechnique(&src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(tech.Delete)
+	return tech
+}
+
+// every accessor gets back the values it sets
+func TestUniformArrays(t *testing.T) {
+	withContext(t)
+	tech := newUniformsTechnique(t)
+	check := func(name string, err error, equal bool) {
+		t.Helper()
+		if err != nil {
+			t.Errorf("%v: %v", name, err)
+		} else if !equal {
+			t.Errorf("%v: got other values than set", name)
+		}
+	}
+
+	b := []bool{true, false}
+	gb := make([]bool, 2)
+	err := tech.SetUniformBoolArray("b", b)
+	if err == nil {
+		err = tech.GetUniformBoolArray("b", gb)
+	}
+	check("b", err, gb[0] == b[0] && gb[1] == b[1])
+
+	b2 := BVec2{X: false, Y: true}
+	err = tech.SetUniformBVec2("b2", b2)
+	gb2, err2 := tech.GetUniformBVec2("b2")
+	check("b2", errors.Join(err, err2), gb2 == b2)
+
+	b3 := []BVec3{{X: true}, {Y: true, Z: true}}
+	gb3 := make([]BVec3, 2)
+	err = errors.Join(tech.SetUniformBVec3Array("b3", b3), tech.GetUniformBVec3Array("b3", gb3))
+	check("b3", err, gb3[0] == b3[0] && gb3[1] == b3[1])
+
+	b4 := []BVec4{{X: true, W: true}, {Y: true, Z: true}}
+	gb4 := make([]BVec4, 2)
+	err = errors.Join(tech.SetUniformBVec4Array("b4", b4), tech.GetUniformBVec4Array("b4", gb4))
+	check("b4", err, gb4[0] == b4[0] && gb4[1] == b4[1])
+
+	i2 := []IVec2{{X: 1, Y: -2}, {X: 3, Y: 4}}
+	gi2 := make([]IVec2, 2)
+	err = errors.Join(tech.SetUniformIVec2Array("i2", i2), tech.GetUniformIVec2Array("i2", gi2))
+	check("i2", err, gi2[0] == i2[0] && gi2[1] == i2[1])
+
+	i3 := []IVec3{{X: 1, Y: -2, Z: 3}, {X: 4, Y: 5, Z: -6}}
+	gi3 := make([]IVec3, 2)
+	err = errors.Join(tech.SetUniformIVec3Array("i3", i3), tech.GetUniformIVec3Array("i3", gi3))
+	check("i3", err, gi3[0] == i3[0] && gi3[1] == i3[1])
+
+	i4 := []IVec4{{X: 1, Y: -2, Z: 3, W: 4}, {X: 5, Y: 6, Z: -7, W: 8}}
+	gi4 := make([]IVec4, 2)
+	err = errors.Join(tech.SetUniformIVec4Array("i4", i4), tech.GetUniformIVec4Array("i4", gi4))
+	check("i4", err, gi4[0] == i4[0] && gi4[1] == i4[1])
+
+	u2 := []UVec2{{X: 1, Y: 2}, {X: 3, Y: 4}}
+	gu2 := make([]UVec2, 2)
+	err = errors.Join(tech.SetUniformUVec2Array("u2", u2), tech.GetUniformUVec2Array("u2", gu2))
+	check("u2", err, gu2[0] == u2[0] && gu2[1] == u2[1])
+
+	u3 := []UVec3{{X: 1, Y: 2, Z: 3}, {X: 4, Y: 5, Z: 6}}
+	gu3 := make([]UVec3, 2)
+	err = errors.Join(tech.SetUniformUVec3Array("u3", u3), tech.GetUniformUVec3Array("u3", gu3))
+	check("u3", err, gu3[0] == u3[0] && gu3[1] == u3[1])
+
+	u4 := []UVec4{{X: 1, Y: 2, Z: 3, W: 4}, {X: 5, Y: 6, Z: 7, W: 8}}
+	gu4 := make([]UVec4, 2)
+	err = errors.Join(tech.SetUniformUVec4Array("u4", u4), tech.GetUniformUVec4Array("u4", gu4))
+	check("u4", err, gu4[0] == u4[0] && gu4[1] == u4[1])
+
+	m2 := []Mat2{{1, 2, 3, 4}, {5, 6, 7, 8}}
+	gm2 := make([]Mat2, 2)
+	err = errors.Join(tech.SetUniformMat2Array("m2", m2), tech.GetUniformMat2Array("m2", gm2))
+	check("m2", err, gm2[0] == m2[0] && gm2[1] == m2[1])
+
+	m3 := []Mat3{{1, 2, 3, 4, 5, 6, 7, 8, 9}, {9, 8, 7, 6, 5, 4, 3, 2, 1}}
+	gm3 := make([]Mat3, 2)
+	err = errors.Join(tech.SetUniformMat3Array("m3", m3), tech.GetUniformMat3Array("m3", gm3))
+	check("m3", err, gm3[0] == m3[0] && gm3[1] == m3[1])
+}
+
+// values of new types survive replacing program
+func TestReplaceKeepsBoolVectors(t *testing.T) {
+	withContext(t)
+	tech := newUniformsTechnique(t)
+	b4 := []BVec4{{X: true, W: true}, {Y: true, Z: true}}
+	if err := tech.SetUniformBVec4Array("b4", b4); err != nil {
+		t.Fatal(err)
+	}
+	src := ComputeShaderSource(UNIFORMS_SHADER)
+	nt, err := NewComputeTechnique(&src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tech.Replace(nt); err != nil {
+		t.Fatal(err)
+	}
+	got := make([]BVec4, 2)
+	if err := tech.GetUniformBVec4Array("b4", got); err != nil {
+		t.Fatal(err)
+	}
+	if got[0] != b4[0] || got[1] != b4[1] {
+		t.Errorf("got %v after replace, want %v", got, b4)
+	}
+}
+
+func TestUniformArrayErrors(t *testing.T) {
+	withContext(t)
+	tech := newUniformsTechnique(t)
+	if err := tech.SetUniformIVec2Array("i2", make([]IVec2, 3)); !errors.Is(err, ErrUniformArraySize) {
+		t.Errorf("got %v, want ErrUniformArraySize", err)
+	}
+	if err := tech.SetUniformUVec2Array("i2", make([]UVec2, 2)); !errors.Is(err, ErrUniformType) {
+		t.Errorf("got %v, want ErrUniformType", err)
+	}
+	if err := tech.SetUniformBVec2Array("missing", make([]BVec2, 1)); !errors.Is(err, ErrUnknownUniform) {
+		t.Errorf("got %v, want ErrUnknownUniform", err)
+	}
+}
@@ -1,9 +1,102 @@
 package core
 
+import "errors"
+import "fmt"
+import "runtime"
 import "github.com/go-gl/gl/v4.6-core/gl"
 
+// OpenGL errors
+var (
+	ErrInvalidEnum                 = errors.New("GL_INVALID_ENUM")
+	ErrInvalidValue                = errors.New("GL_INVALID_VALUE")
+	ErrInvalidOperation            = errors.New("GL_INVALID_OPERATION")
+	ErrStackOverflow               = errors.New("GL_STACK_OVERFLOW")
+	ErrStackUnderflow              = errors.New("GL_STACK_UNDERFLOW")
+	ErrOutOfMemory                 = errors.New("GL_OUT_OF_MEMORY")
+	ErrInvalidFramebufferOperation = errors.New("GL_INVALID_FRAMEBUFFER_OPERATION")
+	ErrContextLost                 = errors.New("GL_CONTEXT_LOST")
+	ErrUnknown                     = errors.New("unknown OpenGL error")
+)
+
+var glErrors = map[uint32]error{
+	gl.INVALID_ENUM:                  ErrInvalidEnum,
+	gl.INVALID_VALUE:                 ErrInvalidValue,
+	gl.INVALID_OPERATION:             ErrInvalidOperation,
+	gl.STACK_OVERFLOW:                ErrStackOverflow,
+	gl.STACK_UNDERFLOW:               ErrStackUnderflow,
+	gl.OUT_OF_MEMORY:                 ErrOutOfMemory,
+	gl.INVALID_FRAMEBUFFER_OPERATION: ErrInvalidFramebufferOperation,
+	gl.CONTEXT_LOST:                  ErrContextLost,
+}
+
+// OpenGL error detected at some call site, use errors.Is to match it against ErrXXX
+type Error struct {
+	Code uint32 // value returned by glGetError
+	Site string // function, file and line where error was detected
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%v at %v", e.Unwrap(), e.Site)
+}
+
+func (e *Error) Unwrap() error {
+	if err, ok := glErrors[e.Code]; ok {
+		return err
+	}
+	return fmt.Errorf("%w 0x%x", ErrUnknown, e.Code)
+}
+
+// error flags read at most by one check, GL has a flag per error code, so more
+// of them mean that glGetError keeps reporting, e.g. GL_CONTEXT_LOST
+const MAX_ERROR_FLAGS = 16
+
+func getError(skip int) error {
+	var codes []uint32
+	for len(codes) < MAX_ERROR_FLAGS {
+		code := gl.GetError()
+		if code == gl.NO_ERROR {
+			break
+		}
+		codes = append(codes, code)
+	}
+	if len(codes) == 0 {
+		return nil
+	}
+
+	site := "unknown"
+	if pc, file, line, ok := runtime.Caller(skip + 1); ok {
+		site = fmt.Sprintf("%v:%v", file, line)
+		if f := runtime.FuncForPC(pc); f != nil {
+			site = fmt.Sprintf("%v (%v)", f.Name(), site)
+		}
+	}
+
+	return newError(codes, site)
+}
+
+// error of codes reported by glGetError at the same site, joined if there are several
+func newError(codes []uint32, site string) error {
+	if len(codes) == 1 {
+		return &Error{Code: codes[0], Site: site}
+	}
+	errs := make([]error, len(codes))
+	for i, code := range codes {
+		errs[i] = &Error{Code: code, Site: site}
+	}
+	return errors.Join(errs...)
+}
+
+// returns OpenGL errors occurred since last check, if any; all error flags are
+// cleared, and if several are set, they are joined in order glGetError reports them
+func GetError() error {
+	return getError(1)
+}
+
+// panics on OpenGL error occurred since last check
+//
+// Deprecated: use GetError and return the error, so that callers can recover from it.
 func CheckError() {
-	if err := gl.GetError(); err != 0 {
+	if err := getError(1); err != nil {
 		panic(err)
 	}
 }
@@ -0,0 +1,78 @@
+package core
+
+import "errors"
+import "os"
+import "runtime"
+import "testing"
+import "github.com/go-gl/gl/v4.6-core/gl"
+
+// makes offscreen OpenGL context current for the rest of the test, skips test without context
+func withContext(t *testing.T) {
+	t.Helper()
+	for name, value := range MesaOverrides {
+		if os.Getenv(name) == "" {
+			os.Setenv(name, value)
+		}
+	}
+	runtime.LockOSThread()
+	hc, err := NewHeadlessContext(16, 16)
+	if err != nil {
+		runtime.UnlockOSThread()
+		t.Skipf("no headless OpenGL context: %v", err)
+	}
+	t.Cleanup(func() {
+		hc.Destroy()
+		runtime.UnlockOSThread()
+	})
+	if err := InitGL(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// implementations may keep a flag per error code, like the spec allows, or only the
+// first error, like Mesa does; either way the first error is reported and nothing is left
+func TestGetErrorClearsAllFlags(t *testing.T) {
+	withContext(t)
+	if err := GetError(); err != nil {
+		t.Fatalf("error before test: %v", err)
+	}
+
+	gl.Enable(0xFFFF)                      // not a capability
+	gl.BindBuffer(gl.ARRAY_BUFFER, 0xFFFF) // not a generated name
+	err := GetError()
+	if !errors.Is(err, ErrInvalidEnum) {
+		t.Errorf("got %v, want GL_INVALID_ENUM among errors", err)
+	}
+	var glErr *Error
+	if !errors.As(err, &glErr) || glErr.Site == "unknown" {
+		t.Errorf("got %v, want errors with call site", err)
+	}
+	if err := GetError(); err != nil {
+		t.Errorf("flag is left after check: %v", err)
+	}
+
+	gl.BindBuffer(gl.ARRAY_BUFFER, 0xFFFF)
+	if err := GetError(); !errors.Is(err, ErrInvalidOperation) || errors.Is(err, ErrInvalidEnum) {
+		t.Errorf("got %v, want GL_INVALID_OPERATION only", err)
+	}
+}
+
+func TestNewErrorJoinsCodes(t *testing.T) {
+	err := newError([]uint32{gl.INVALID_ENUM, gl.OUT_OF_MEMORY, 0x1234}, "site")
+	for _, target := range []error{ErrInvalidEnum, ErrOutOfMemory, ErrUnknown} {
+		if !errors.Is(err, target) {
+			t.Errorf("%v doesn't match %v", err, target)
+		}
+	}
+	if errors.Is(err, ErrInvalidValue) {
+		t.Errorf("%v matches GL_INVALID_VALUE", err)
+	}
+	var glErr *Error
+	if !errors.As(err, &glErr) || glErr.Code != gl.INVALID_ENUM {
+		t.Errorf("first error of %v isn't GL_INVALID_ENUM", err)
+	}
+
+	if err := newError([]uint32{gl.INVALID_VALUE}, "site"); err.Error() != "GL_INVALID_VALUE at site" {
+		t.Errorf("single error is %q", err)
+	}
+}
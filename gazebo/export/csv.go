@@ -0,0 +1,31 @@
+package export
+
+import "encoding/csv"
+import "io"
+import "strconv"
+import "github.com/dmarychev/gazebo/particles"
+
+// columns of CSV, one row per particle
+var csvHeader = []string{"x", "y", "vx", "vy", "fx", "fy", "pressure", "density", "mass"}
+
+// Writes particles as CSV with header row, one row per particle
+func WriteCSV(w io.Writer, ps []particles.Particle) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+
+	row := make([]string, len(csvHeader))
+	for i := range ps {
+		p := &ps[i]
+		for j, value := range []float32{p.R.X, p.R.Y, p.V.X, p.V.Y, p.F.X, p.F.Y, p.P, p.D, p.M} {
+			row[j] = strconv.FormatFloat(float64(value), 'g', -1, 32)
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,55 @@
+package export
+
+import "bytes"
+import "encoding/csv"
+import "strconv"
+import "testing"
+import "github.com/dmarychev/gazebo/core"
+import "github.com/dmarychev/gazebo/particles"
+
+// particles with distinct values of every exported field
+func testParticles(n int) []particles.Particle {
+	ps := make([]particles.Particle, n)
+	for i := range ps {
+		f := float32(i)
+		ps[i] = particles.Particle{
+			R: core.Vec2{X: 0.1 * f, Y: -0.2 * f},
+			V: core.Vec2{X: 1 + f, Y: 2 + f},
+			F: core.Vec2{X: 3 + f, Y: -4 - f},
+			P: 100.5 + f, D: 1000.25 + f, M: 0.01,
+		}
+	}
+	return ps
+}
+
+func TestWriteCSV(t *testing.T) {
+	ps := testParticles(3)
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, ps); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != len(ps)+1 {
+		t.Fatalf("got %v records, want header and %v rows", len(records), len(ps))
+	}
+	want := []string{"x", "y", "vx", "vy", "fx", "fy", "pressure", "density", "mass"}
+	for i, column := range want {
+		if records[0][i] != column {
+			t.Fatalf("header is %v, want %v", records[0], want)
+		}
+	}
+
+	for i, p := range ps {
+		expected := []float32{p.R.X, p.R.Y, p.V.X, p.V.Y, p.F.X, p.F.Y, p.P, p.D, p.M}
+		row := records[i+1]
+		for j, e := range expected {
+			value, err := strconv.ParseFloat(row[j], 32)
+			if err != nil || float32(value) != e {
+				t.Errorf("%v of particle %v is %q, want %v", want[j], i, row[j], e)
+			}
+		}
+	}
+}
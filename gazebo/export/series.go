@@ -0,0 +1,122 @@
+package export
+
+import "fmt"
+import "io"
+import "os"
+import "path/filepath"
+import "github.com/dmarychev/gazebo/particles"
+
+// file format of exported frames
+type Format int
+
+const (
+	FORMAT_VTP Format = iota // VTK XML PolyData, see WriteVTP
+	FORMAT_CSV               // comma separated values, see WriteCSV
+	COUNT_FORMATS
+)
+
+var formatNames = [COUNT_FORMATS]string{"vtp", "csv"}
+
+func (f Format) String() string {
+	if f < 0 || f >= COUNT_FORMATS {
+		return fmt.Sprintf("Format(%d)", int(f))
+	}
+	return formatNames[f]
+}
+
+func ParseFormat(name string) (Format, error) {
+	for i, n := range formatNames {
+		if n == name {
+			return Format(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown export format %q", name)
+}
+
+// writes particles in format
+func Write(w io.Writer, format Format, ps []particles.Particle) error {
+	switch format {
+	case FORMAT_VTP:
+		return WriteVTP(w, ps)
+	case FORMAT_CSV:
+		return WriteCSV(w, ps)
+	}
+	return fmt.Errorf("unknown export format %v", format)
+}
+
+const (
+	SERIES_COLLECTION = "series.pvd" // collection file of VTP series in its directory
+)
+
+// Exports every interval-th step of simulation to numbered files in directory.
+// VTP frames are listed with their simulation time in SERIES_COLLECTION, which is
+// rewritten after every frame, so it can be opened in ParaView while run is going.
+type Series struct {
+	dir      string       // output directory
+	format   Format       // format of frames
+	interval int          // export every interval-th step
+	step     int          // number of steps passed to Write or Skip
+	written  int          // number of frames exported
+	datasets []PVDDataSet // exported VTP frames
+}
+
+func NewSeries(dir string, format Format, interval int) (*Series, error) {
+	if interval < 1 {
+		return nil, fmt.Errorf("export interval must be positive, got %v", interval)
+	}
+	if format < 0 || format >= COUNT_FORMATS {
+		return nil, fmt.Errorf("unknown export format %v", format)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Series{dir: dir, format: format, interval: interval}, nil
+}
+
+// number of frames exported
+func (s *Series) Count() int {
+	return s.written
+}
+
+// true if the next step will be exported, so particles must be read back
+func (s *Series) Due() bool {
+	return s.step%s.interval == 0
+}
+
+// counts step which is not exported
+func (s *Series) Skip() {
+	s.step++
+}
+
+// exports particles at simulation time if step is due, counts step otherwise
+func (s *Series) Write(time float64, ps []particles.Particle) error {
+	due := s.Due()
+	s.step++
+	if !due {
+		return nil
+	}
+
+	name := fmt.Sprintf("frame%05d.%v", s.written+1, s.format)
+	if err := writeFile(filepath.Join(s.dir, name), func(w io.Writer) error { return Write(w, s.format, ps) }); err != nil {
+		return err
+	}
+	s.written++
+
+	if s.format != FORMAT_VTP {
+		return nil
+	}
+	s.datasets = append(s.datasets, PVDDataSet{Time: time, File: name})
+	return writeFile(filepath.Join(s.dir, SERIES_COLLECTION), func(w io.Writer) error { return WritePVD(w, s.datasets) })
+}
+
+func writeFile(path string, write func(w io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err = write(f); err != nil {
+		f.Close()
+		return fmt.Errorf("%v: %w", path, err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,108 @@
+package export
+
+import "encoding/xml"
+import "os"
+import "path/filepath"
+import "testing"
+
+type pvdFile struct {
+	DataSets []struct {
+		Time float64 `xml:"timestep,attr"`
+		File string  `xml:"file,attr"`
+	} `xml:"Collection>DataSet"`
+}
+
+func TestSeriesVTP(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "out")
+	s, err := NewSeries(dir, FORMAT_VTP, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// steps 0, 3 and 6 of 8 are exported
+	for step := 0; step < 8; step++ {
+		if due := s.Due(); due != (step%3 == 0) {
+			t.Errorf("step %v: due is %v", step, due)
+		}
+		if step == 4 {
+			s.Skip()
+			continue
+		}
+		if err := s.Write(0.5*float64(step), testParticles(2)); err != nil {
+			t.Fatal(err)
+		}
+
+		// collection lists frames written so far
+		data, err := os.ReadFile(filepath.Join(dir, SERIES_COLLECTION))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var pvd pvdFile
+		if err := xml.Unmarshal(data, &pvd); err != nil {
+			t.Fatal(err)
+		}
+		if len(pvd.DataSets) != s.Count() {
+			t.Errorf("step %v: collection lists %v frames, %v written", step, len(pvd.DataSets), s.Count())
+		}
+	}
+	if s.Count() != 3 {
+		t.Fatalf("%v frames written, want 3", s.Count())
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, SERIES_COLLECTION))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pvd pvdFile
+	if err := xml.Unmarshal(data, &pvd); err != nil {
+		t.Fatal(err)
+	}
+	for i, time := range []float64{0, 1.5, 3} {
+		ds := pvd.DataSets[i]
+		if ds.Time != time {
+			t.Errorf("frame %v has time %v, want %v", i, ds.Time, time)
+		}
+		if _, err := os.Stat(filepath.Join(dir, ds.File)); err != nil {
+			t.Errorf("frame %v: %v", i, err)
+		}
+	}
+}
+
+func TestSeriesCSV(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewSeries(dir, FORMAT_CSV, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for step := 0; step < 5; step++ {
+		if err := s.Write(float64(step), testParticles(1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	want := []string{"frame00001.csv", "frame00002.csv", "frame00003.csv"}
+	if len(names) != len(want) {
+		t.Fatalf("files %v, want %v without collection", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("files %v, want %v", names, want)
+		}
+	}
+}
+
+func TestNewSeriesInvalid(t *testing.T) {
+	if _, err := NewSeries(t.TempDir(), FORMAT_VTP, 0); err == nil {
+		t.Errorf("no error for zero interval")
+	}
+	if _, err := NewSeries(t.TempDir(), COUNT_FORMATS, 1); err == nil {
+		t.Errorf("no error for unknown format")
+	}
+}
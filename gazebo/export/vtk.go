@@ -0,0 +1,95 @@
+package export
+
+import "bufio"
+import "fmt"
+import "io"
+import "github.com/dmarychev/gazebo/particles"
+
+// point data array of particle field
+type vtkArray struct {
+	name       string
+	components int
+	values     func(p *particles.Particle) []float32
+}
+
+// coordinates and vectors are written with zero z component, as VTK points are 3D
+var vtkArrays = []vtkArray{
+	{"velocity", 3, func(p *particles.Particle) []float32 { return []float32{p.V.X, p.V.Y, 0} }},
+	{"force", 3, func(p *particles.Particle) []float32 { return []float32{p.F.X, p.F.Y, 0} }},
+	{"pressure", 1, func(p *particles.Particle) []float32 { return []float32{p.P} }},
+	{"density", 1, func(p *particles.Particle) []float32 { return []float32{p.D} }},
+	{"mass", 1, func(p *particles.Particle) []float32 { return []float32{p.M} }},
+}
+
+func writeVTKArray(w *bufio.Writer, name string, components int, ps []particles.Particle, values func(p *particles.Particle) []float32) {
+	fmt.Fprintf(w, "        <DataArray type=\"Float32\" Name=\"%v\" NumberOfComponents=\"%v\" format=\"ascii\">\n", name, components)
+	for i := range ps {
+		w.WriteString("         ")
+		for _, value := range values(&ps[i]) {
+			fmt.Fprintf(w, " %g", value)
+		}
+		w.WriteString("\n")
+	}
+	w.WriteString("        </DataArray>\n")
+}
+
+// Writes particles as VTK XML PolyData (.vtp) of vertices with point data arrays
+// "velocity", "force", "pressure", "density" and "mass", in ASCII format
+func WriteVTP(w io.Writer, ps []particles.Particle) error {
+	bw := bufio.NewWriter(w)
+	n := len(ps)
+
+	bw.WriteString("<?xml version=\"1.0\"?>\n")
+	bw.WriteString("<VTKFile type=\"PolyData\" version=\"0.1\" byte_order=\"LittleEndian\">\n")
+	bw.WriteString("  <PolyData>\n")
+	fmt.Fprintf(bw, "    <Piece NumberOfPoints=\"%v\" NumberOfVerts=\"%v\" NumberOfLines=\"0\" NumberOfStrips=\"0\" NumberOfPolys=\"0\">\n", n, n)
+
+	bw.WriteString("      <Points>\n")
+	writeVTKArray(bw, "coordinates", 3, ps, func(p *particles.Particle) []float32 { return []float32{p.R.X, p.R.Y, 0} })
+	bw.WriteString("      </Points>\n")
+
+	bw.WriteString("      <PointData Scalars=\"density\" Vectors=\"velocity\">\n")
+	for _, array := range vtkArrays {
+		writeVTKArray(bw, array.name, array.components, ps, array.values)
+	}
+	bw.WriteString("      </PointData>\n")
+
+	// every particle is a vertex cell
+	bw.WriteString("      <Verts>\n")
+	bw.WriteString("        <DataArray type=\"Int32\" Name=\"connectivity\" format=\"ascii\">\n")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(bw, "          %v\n", i)
+	}
+	bw.WriteString("        </DataArray>\n")
+	bw.WriteString("        <DataArray type=\"Int32\" Name=\"offsets\" format=\"ascii\">\n")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(bw, "          %v\n", i+1)
+	}
+	bw.WriteString("        </DataArray>\n")
+	bw.WriteString("      </Verts>\n")
+
+	bw.WriteString("    </Piece>\n")
+	bw.WriteString("  </PolyData>\n")
+	bw.WriteString("</VTKFile>\n")
+	return bw.Flush()
+}
+
+// dataset of VTK collection
+type PVDDataSet struct {
+	Time float64 // simulation time
+	File string  // path of dataset relative to collection file
+}
+
+// Writes VTK collection (.pvd) of datasets, e.g. time series of .vtp files
+func WritePVD(w io.Writer, datasets []PVDDataSet) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("<?xml version=\"1.0\"?>\n")
+	bw.WriteString("<VTKFile type=\"Collection\" version=\"0.1\" byte_order=\"LittleEndian\">\n")
+	bw.WriteString("  <Collection>\n")
+	for _, ds := range datasets {
+		fmt.Fprintf(bw, "    <DataSet timestep=\"%g\" group=\"\" part=\"0\" file=\"%v\"/>\n", ds.Time, ds.File)
+	}
+	bw.WriteString("  </Collection>\n")
+	bw.WriteString("</VTKFile>\n")
+	return bw.Flush()
+}
@@ -0,0 +1,64 @@
+package export
+
+import "bytes"
+import "encoding/xml"
+import "strconv"
+import "strings"
+import "testing"
+
+type vtkDataArray struct {
+	Name       string `xml:"Name,attr"`
+	Components int    `xml:"NumberOfComponents,attr"`
+	Values     string `xml:",chardata"`
+}
+
+type vtkFile struct {
+	Type  string `xml:"type,attr"`
+	Piece struct {
+		NumberOfPoints int            `xml:"NumberOfPoints,attr"`
+		NumberOfVerts  int            `xml:"NumberOfVerts,attr"`
+		Points         []vtkDataArray `xml:"Points>DataArray"`
+		PointData      []vtkDataArray `xml:"PointData>DataArray"`
+		Verts          []vtkDataArray `xml:"Verts>DataArray"`
+	} `xml:"PolyData>Piece"`
+}
+
+func TestWriteVTP(t *testing.T) {
+	for _, n := range []int{0, 1, 7} {
+		var buf bytes.Buffer
+		if err := WriteVTP(&buf, testParticles(n)); err != nil {
+			t.Fatal(err)
+		}
+		var f vtkFile
+		if err := xml.Unmarshal(buf.Bytes(), &f); err != nil {
+			t.Fatalf("%v particles: %v", n, err)
+		}
+		piece := f.Piece
+		if f.Type != "PolyData" || piece.NumberOfPoints != n || piece.NumberOfVerts != n {
+			t.Errorf("%v particles: file of type %q has %v points and %v verts", n, f.Type, piece.NumberOfPoints, piece.NumberOfVerts)
+		}
+
+		arrays := append(piece.Points, piece.PointData...)
+		if len(piece.Points) != 1 || len(piece.PointData) != len(vtkArrays) {
+			t.Fatalf("%v particles: %v point arrays, %v point data arrays", n, len(piece.Points), len(piece.PointData))
+		}
+		for _, array := range arrays {
+			if values := len(strings.Fields(array.Values)); values != n*array.Components {
+				t.Errorf("%v particles: %q has %v values, want %v", n, array.Name, values, n*array.Components)
+			}
+		}
+
+		if len(piece.Verts) != 2 {
+			t.Fatalf("%v particles: %v verts arrays, want connectivity and offsets", n, len(piece.Verts))
+		}
+		for _, array := range piece.Verts {
+			values := strings.Fields(array.Values)
+			if len(values) != n {
+				t.Errorf("%v particles: %q has %v values", n, array.Name, len(values))
+			}
+			if array.Name == "offsets" && n > 0 && values[n-1] != strconv.Itoa(n) {
+				t.Errorf("%v particles: last offset is %v", n, values[n-1])
+			}
+		}
+	}
+}
@@ -1,22 +1,57 @@
 package main
 
+import "flag"
+import "fmt"
 import "log"
+import "math"
+import "os"
 import "time"
 
-import "math/rand"
 import "runtime"
 import "github.com/go-gl/glfw/v3.2/glfw"
 import "github.com/go-gl/gl/v4.6-core/gl"
 import "github.com/dmarychev/gazebo/particles"
 import "github.com/dmarychev/gazebo/core"
+import "github.com/dmarychev/gazebo/export"
 
-func initOpenGL() {
-	if err := gl.Init(); err != nil {
-		panic(err)
-	}
-	version := gl.GoStr(gl.GetString(gl.VERSION))
-	log.Println("OpenGL version", version)
-	gl.Enable(gl.PROGRAM_POINT_SIZE)
+var sceneFile = flag.String("scene", "scenes/dam_break.json", "scene description file")
+var headless = flag.Bool("headless", false, "run simulation in offscreen context without window")
+var headlessSteps = flag.Int("steps", 1000, "number of simulation steps to run in headless mode")
+var backend = flag.String("backend", "", "simulation backend overriding scene's one: gpu or cpu")
+var renderMode = flag.String("render", "points", "rendering of particles: points or fluid")
+var colorField = flag.String("color", "", "scalar field coloring points overriding scene's one: none, speed, density, pressure, force or mass")
+var profile = flag.Bool("profile", false, "measure GPU time of pipeline stages and log it every second")
+var debugLeaks = flag.Bool("debug-leaks", false, "track OpenGL objects and report the ones not deleted at exit")
+var snapshotPath = flag.String("snapshot", "", "snapshot file saved and loaded with S and L keys, "+DEFAULT_SNAPSHOT+" if empty, or saved at the end in headless mode")
+var restorePath = flag.String("restore", "", "start from snapshot file instead of scene's emitters")
+var recordPath = flag.String("record", "", "record frames to directory of PNG files or to animated GIF file *.gif")
+var recordInterval = flag.Int("record-interval", 1, "record every n-th rendered frame")
+var recordFrames = flag.Int("record-frames", 0, "number of frames to record, 0 means until exit")
+var exportDir = flag.String("export", "", "export particles to directory of numbered files every n-th simulation step")
+var exportInterval = flag.Int("export-interval", 10, "export every n-th simulation step")
+var exportFormat = flag.String("export-format", "vtp", "format of exported particles: vtp with series.pvd collection or csv")
+
+const (
+	DEFAULT_SNAPSHOT = "snapshot.gzs" // snapshot file of S and L keys
+	ZOOM_STEP        = 1.1            // scale of visible region per step of scroll wheel
+)
+
+// window context backed by GLFW
+type windowContext struct {
+	*glfw.Window
+}
+
+func (wc windowContext) MakeCurrent() {
+	wc.MakeContextCurrent()
+}
+
+func (wc windowContext) Size() (width, height int) {
+	return wc.GetFramebufferSize()
+}
+
+func (wc windowContext) Destroy() {
+	wc.Window.Destroy()
+	glfw.Terminate()
 }
 
 func initGlfw() *glfw.Window {
@@ -36,118 +71,272 @@ func initGlfw() *glfw.Window {
 	return window
 }
 
-// initialize simple SPH particles system
-func simpleSPHSystem() *particles.System {
-
-	smoothingRadius := float32(0.01)
-	maxNeighborParticles := uint32(40)
-	viscosity := float32(5.0)
-	gravity := float32(0.08)
-	//gravity := float32(0.0)
-	pressureCoefficient := float32(0.015)
-	modellingTimeStep := float32(0.01)
-	damping := float32(-0.99)
-
-	renderThis, err := particles.NewRenderTechniqueFromFile("vfx/test.vs", "vfx/test.fs")
+// panics on error
+func must(err error) {
 	if err != nil {
 		panic(err)
 	}
+}
 
-	indexClear, err := particles.NewComputeTechniqueFromFile("sph/index_clear.cs")
+// framebuffer of given size with color texture and depth renderbuffer,
+// returned function deletes framebuffer and its attachments
+func makeOffscreenFramebuffer(width, height int32) (core.FramebufferObject, func(), error) {
+	color, err := core.MakeTexture2D(width, height, gl.RGBA8)
 	if err != nil {
-		panic(err)
+		return 0, nil, err
 	}
-
-	indexUpdate, err := particles.NewComputeTechniqueFromFile("sph/index_update.cs")
+	depth, err := core.MakeRenderbuffer(width, height, gl.DEPTH_COMPONENT24)
 	if err != nil {
-		panic(err)
+		color.Delete()
+		return 0, nil, err
 	}
-	indexUpdate.SetUniformFloat32("h", smoothingRadius)
-	indexUpdate.SetUniformUint("index_max_neighbors", maxNeighborParticles)
 
-	densityAndPressure, err := particles.NewComputeTechniqueFromFile("sph/density_and_pressure.cs")
+	fbo := core.MakeFramebufferObject()
+	release := func() {
+		fbo.Delete()
+		depth.Delete()
+		color.Delete()
+	}
+	if err = fbo.AttachTexture(gl.COLOR_ATTACHMENT0, color); err == nil {
+		if err = fbo.AttachRenderbuffer(gl.DEPTH_ATTACHMENT, depth); err == nil {
+			err = fbo.CheckStatus()
+		}
+	}
 	if err != nil {
-		panic(err)
+		release()
+		return 0, nil, err
 	}
-	densityAndPressure.SetUniformFloat32("h", smoothingRadius)
-	densityAndPressure.SetUniformFloat32("k", pressureCoefficient)
-	densityAndPressure.SetUniformUint("index_max_neighbors", maxNeighborParticles)
+	return fbo, release, nil
+}
 
-	accumulateForces, err := particles.NewComputeTechniqueFromFile("sph/accumulate_forces.cs")
+func saveSnapshot(ps *particles.System, path string) error {
+	f, err := os.Create(path)
 	if err != nil {
-		panic(err)
+		return err
+	}
+	if err = ps.SaveSnapshot(f); err != nil {
+		f.Close()
+		return err
 	}
-	accumulateForces.SetUniformFloat32("h", smoothingRadius)
-	accumulateForces.SetUniformFloat32("mu", viscosity)
-	accumulateForces.SetUniformFloat32("g", gravity)
-	accumulateForces.SetUniformUint("index_max_neighbors", maxNeighborParticles)
+	log.Printf("Saved snapshot of %v particles at time %v to %v", ps.CountParticles(), ps.Time(), path)
+	return f.Close()
+}
 
-	leapfrog, err := particles.NewComputeTechniqueFromFile("sph/leapfrog_integration.cs")
+func loadSnapshot(ps *particles.System, path string) error {
+	f, err := os.Open(path)
 	if err != nil {
-		panic(err)
+		return err
+	}
+	defer f.Close()
+	if err = ps.LoadSnapshot(f); err != nil {
+		return fmt.Errorf("%v: %w", path, err)
 	}
-	leapfrog.SetUniformFloat32("dt", modellingTimeStep)
+	log.Printf("Loaded snapshot of %v particles at time %v from %v", ps.CountParticles(), ps.Time(), path)
+	return nil
+}
 
-	reflectBoundaries, err := particles.NewComputeTechniqueFromFile("sph/reflect_boundaries.cs")
+// exports particles if simulation step is due for export, reading them back only then
+func exportStep(ps *particles.System, series *export.Series) error {
+	if series == nil {
+		return nil
+	}
+	if !series.Due() {
+		series.Skip()
+		return nil
+	}
+	particlesSet, err := ps.Particles()
 	if err != nil {
-		panic(err)
+		return err
 	}
-	reflectBoundaries.SetUniformFloat32("damping_coeff", damping)
+	return series.Write(ps.Time(), particlesSet)
+}
 
-	ps := particles.NewSystem(renderThis, indexUpdate, indexClear, maxNeighborParticles)
+// steps simulation without window and reports averaged particles' state,
+// steps to be recorded are rendered to offscreen framebuffer
+func runHeadless(ps *particles.System, steps int, context core.Context, recorder *frameRecorder, series *export.Series) {
+	width, height := context.Size()
+	var fbo core.FramebufferObject
+	if recorder != nil {
+		var release func()
+		var err error
+		fbo, release, err = makeOffscreenFramebuffer(int32(width), int32(height))
+		must(err)
+		defer release()
+	}
 
-	ps.AddUpdateTechnique(densityAndPressure)
-	ps.AddUpdateTechnique(accumulateForces)
-	ps.AddUpdateTechnique(leapfrog)
-	ps.AddUpdateTechnique(reflectBoundaries)
+	t0 := time.Now()
+	for step := 0; step < steps; step++ {
+		must(ps.Update())
+		must(exportStep(ps, series))
+		if recorder == nil {
+			continue
+		}
+		if !recorder.Due() {
+			recorder.Skip()
+			continue
+		}
+		unbind := fbo.Bind()
+		gl.Viewport(0, 0, int32(width), int32(height))
+		gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+		must(ps.Render())
+		must(recorder.Capture(fbo, gl.COLOR_ATTACHMENT0, width, height))
+		unbind()
+	}
+	must(core.GetError())
+
+	log.Printf("%v steps of %v particles in %v", steps, ps.CountParticles(), time.Since(t0))
+	if times := ps.StageTimes(); len(times) > 0 {
+		log.Printf("GPU time: %v", particles.FormatStageTimes(times))
+	}
+	if ps.Dimensions() == 3 {
+		logMeanState3(ps)
+		return
+	}
+
+	particlesSet, err := ps.Particles()
+	must(err)
 
-	return ps
+	var r, v core.Vec2
+	var d float32
+	for _, p := range particlesSet {
+		r.X, r.Y = r.X+p.R.X, r.Y+p.R.Y
+		v.X, v.Y = v.X+p.V.X, v.Y+p.V.Y
+		d += p.D
+	}
+	n := float32(len(particlesSet))
+	log.Printf("mean position %v, mean velocity %v, mean density %v", core.Vec2{X: r.X / n, Y: r.Y / n}, core.Vec2{X: v.X / n, Y: v.Y / n}, d/n)
+}
+
+func logMeanState3(ps *particles.System) {
+	particlesSet, err := ps.Particles3()
+	must(err)
+
+	var r, v core.Vec3
+	var d float32
+	for _, p := range particlesSet {
+		r.X, r.Y, r.Z = r.X+p.R.X, r.Y+p.R.Y, r.Z+p.R.Z
+		v.X, v.Y, v.Z = v.X+p.V.X, v.Y+p.V.Y, v.Z+p.V.Z
+		d += p.D
+	}
+	n := float32(len(particlesSet))
+	log.Printf("mean position %v, mean velocity %v, mean density %v", core.Vec3{X: r.X / n, Y: r.Y / n, Z: r.Z / n}, core.Vec3{X: v.X / n, Y: v.Y / n, Z: v.Z / n}, d/n)
+}
+
+// sets particles generated by scene's emitters
+func emitParticles(scene *particles.Scene, ps *particles.System) error {
+	if scene.Dimensions == 3 {
+		particlesSet, err := scene.Particles3()
+		if err != nil {
+			return err
+		}
+		return ps.SetParticles3(particlesSet)
+	}
+	particlesSet, err := scene.Particles()
+	if err != nil {
+		return err
+	}
+	return ps.SetParticles(particlesSet)
 }
 
 func main() {
+	flag.Parse()
 	runtime.LockOSThread()
 
-	window := initGlfw()
-	defer glfw.Terminate()
+	var window *glfw.Window
+	var context core.Context
+	if *headless {
+		hc, err := core.NewHeadlessContext(1920, 1080)
+		must(err)
+		context = hc
+	} else {
+		window = initGlfw()
+		context = windowContext{window}
+	}
+	defer context.Destroy()
 
-	rand.Seed(time.Now().UnixNano())
+	must(core.InitGL())
+	if *debugLeaks {
+		core.EnableObjectTracking()
+		defer func() {
+			if err := core.CheckLeaks(); err != nil {
+				log.Printf("%v", err)
+			} else {
+				log.Printf("No leaked OpenGL objects")
+			}
+		}()
+	}
 
-	initOpenGL()
+	scene, err := particles.LoadScene(*sceneFile)
+	must(err)
+	if *backend != "" {
+		scene.Backend = *backend
+	}
 
-	particlesSet := make([]particles.Particle, 0, 4096)
+	ps, err := scene.NewSystem()
+	must(err)
+	defer func() { must(ps.Close()) }()
 
-	for i := 0; i < 16; i++ {
-		for j := 0; j < 256; j++ {
-			particlesSet = append(particlesSet, particles.Particle{
-				R: core.Vec2{X: -0.8 + 0.01*float32(i), Y: -0.8 + 0.01*float32(j)},
-				//V: core.Vec2{Y: -2},
-				M: 0.01,
-			})
+	if *restorePath != "" {
+		must(loadSnapshot(ps, *restorePath))
+	} else {
+		must(emitParticles(scene, ps))
+	}
+
+	var recorder *frameRecorder
+	if *recordPath != "" {
+		recorder, err = newFrameRecorder(*recordPath, *recordInterval, *recordFrames)
+		must(err)
+		defer func() { must(recorder.Close()) }()
+	}
+
+	var series *export.Series
+	if *exportDir != "" {
+		if ps.Dimensions() != 2 {
+			log.Fatalf("export supports only 2D scenes")
 		}
+		format, err := export.ParseFormat(*exportFormat)
+		must(err)
+		series, err = export.NewSeries(*exportDir, format, *exportInterval)
+		must(err)
+		defer func() { log.Printf("Exported %v frames to %v", series.Count(), *exportDir) }()
 	}
 
-	// drop
-	/*	for i := 0; i < 16; i++ {
-			for j := 0; j < 16; j++ {
-				particlesSet = append(particlesSet, particles.Particle{
-					R: core.Vec2{X: 0.01 * float32(i), Y: 20.0 + 0.01*float32(j)},
-					//V: core.Vec2{Y: -2},
-					M: 0.01,
-				})
-			}
+	switch *renderMode {
+	case "points":
+	case "fluid":
+		must(ps.SetRenderMode(particles.RENDER_FLUID))
+	default:
+		log.Fatalf("unknown render mode %q", *renderMode)
+	}
+
+	if *colorField != "" {
+		field, err := particles.ParseColorField(*colorField)
+		must(err)
+		coloring := ps.Coloring()
+		coloring.Field, coloring.AutoRange = field, true
+		ps.SetColoring(coloring)
+	}
+
+	ps.SetProfiling(*profile)
+
+	gl.ClearColor(0.8, 0.8, 0.8, 1.0)
+
+	if *headless {
+		runHeadless(ps, *headlessSteps, context, recorder, series)
+		if *snapshotPath != "" {
+			must(saveSnapshot(ps, *snapshotPath))
 		}
-	*/
-	for i := range particlesSet {
-		particlesSet[i].R.X += -0.0005 + 0.001*rand.Float32()
-		particlesSet[i].R.Y += -0.0005 + 0.001*rand.Float32()
+		return
 	}
 
-	ps := simpleSPHSystem()
-	ps.SetParticles(particlesSet)
+	log.Printf("Press Enter to toggle simulation, F to toggle fluid rendering, C to switch color field, M to switch colormap, S/L to save/load snapshot, R to reset view, drag to pan and scroll to zoom, shader files are reloaded on change")
 
-	log.Printf("Press Enter to toggle simulation")
+	if *snapshotPath == "" {
+		*snapshotPath = DEFAULT_SNAPSHOT
+	}
 
 	simulationOn := false
+	sceneView := ps.OrthoCamera()
 	window.SetKeyCallback(func(w *glfw.Window, key glfw.Key, scancode int, action glfw.Action, mods glfw.ModifierKey) {
 		if key == glfw.KeyEnter && action == glfw.Press {
 			simulationOn = !simulationOn
@@ -157,24 +346,97 @@ func main() {
 				log.Printf("Simulation On")
 			}
 		}
+		if key == glfw.KeyF && action == glfw.Press {
+			mode := particles.RENDER_FLUID
+			if ps.RenderMode() == particles.RENDER_FLUID {
+				mode = particles.RENDER_POINTS
+			}
+			if err := ps.SetRenderMode(mode); err != nil {
+				log.Printf("%v", err)
+			}
+		}
+		if key == glfw.KeyC && action == glfw.Press {
+			coloring := ps.Coloring()
+			coloring.Field = (coloring.Field + 1) % particles.COUNT_COLOR_FIELDS
+			coloring.AutoRange = true
+			ps.SetColoring(coloring)
+			log.Printf("Color field: %v", coloring.Field)
+		}
+		if key == glfw.KeyS && action == glfw.Press {
+			if err := saveSnapshot(ps, *snapshotPath); err != nil {
+				log.Printf("Failed to save snapshot: %v", err)
+			}
+		}
+		if key == glfw.KeyL && action == glfw.Press {
+			if err := loadSnapshot(ps, *snapshotPath); err != nil {
+				log.Printf("Failed to load snapshot: %v", err)
+			}
+		}
+		if key == glfw.KeyM && action == glfw.Press {
+			coloring := ps.Coloring()
+			coloring.Map = (coloring.Map + 1) % particles.COUNT_COLORMAPS
+			ps.SetColoring(coloring)
+			log.Printf("Colormap: %v", coloring.Map)
+		}
+		if key == glfw.KeyR && action == glfw.Press {
+			ps.SetOrthoCamera(sceneView)
+		}
+	})
+
+	// pan and zoom of 2D view, cursor position is in window coordinates, not in pixels of framebuffer
+	dragging := false
+	var cursor core.Vec2
+	window.SetMouseButtonCallback(func(w *glfw.Window, button glfw.MouseButton, action glfw.Action, mods glfw.ModifierKey) {
+		if button == glfw.MouseButtonLeft {
+			dragging = action == glfw.Press
+		}
+	})
+	window.SetCursorPosCallback(func(w *glfw.Window, x, y float64) {
+		position := core.Vec2{X: float32(x), Y: float32(y)}
+		if dragging {
+			width, height := w.GetSize()
+			view := ps.OrthoCamera()
+			view.Pan(position.Sub(cursor), width, height)
+			ps.SetOrthoCamera(view)
+		}
+		cursor = position
+	})
+	window.SetScrollCallback(func(w *glfw.Window, xoff, yoff float64) {
+		width, height := w.GetSize()
+		view := ps.OrthoCamera()
+		view.Zoom(float32(math.Pow(ZOOM_STEP, -yoff)), cursor, width, height)
+		ps.SetOrthoCamera(view)
+	})
+	window.SetFramebufferSizeCallback(func(w *glfw.Window, width, height int) {
+		gl.Viewport(0, 0, int32(width), int32(height))
 	})
 
 	t0 := time.Now()
 	fps := 0
-	gl.ClearColor(0.8, 0.8, 0.8, 1.0)
-	for !window.ShouldClose() {
+	for !context.ShouldClose() {
 		gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
 		if simulationOn {
-			ps.Update()
+			must(ps.Update())
+			must(exportStep(ps, series))
+		}
+		must(ps.Render())
+		if recorder != nil {
+			width, height := context.Size()
+			must(recorder.Capture(0, gl.BACK, width, height))
 		}
-		ps.Render()
 		fps++
-		window.SwapBuffers()
+		context.SwapBuffers()
 		glfw.PollEvents()
 		if t1 := time.Now(); t1.Sub(t0) >= 1E9 {
 			log.Printf("%v FPS", fps)
+			if times := ps.StageTimes(); len(times) > 0 {
+				log.Printf("GPU time: %v", particles.FormatStageTimes(times))
+			}
 			fps, t0 = 0, t1
+			if err := ps.ReloadShaders(); err != nil {
+				log.Printf("Failed to reload shaders, keep previous programs: %v", err)
+			}
 		}
-		core.CheckError()
+		must(core.GetError())
 	}
 }
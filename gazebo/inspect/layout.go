@@ -0,0 +1,152 @@
+package inspect
+
+import "fmt"
+import "reflect"
+import "strings"
+import "github.com/go-gl/gl/v4.6-core/gl"
+import "github.com/dmarychev/gazebo/core"
+
+// size in bytes of scalar/vector type and number of columns of matrix type
+type glslType struct {
+	size    uint32
+	columns uint32
+}
+
+var glslTypes = map[uint32]glslType{
+	gl.FLOAT:             {4, 1},
+	gl.FLOAT_VEC2:        {8, 1},
+	gl.FLOAT_VEC3:        {12, 1},
+	gl.FLOAT_VEC4:        {16, 1},
+	gl.DOUBLE:            {8, 1},
+	gl.DOUBLE_VEC2:       {16, 1},
+	gl.DOUBLE_VEC3:       {24, 1},
+	gl.DOUBLE_VEC4:       {32, 1},
+	gl.INT:               {4, 1},
+	gl.INT_VEC2:          {8, 1},
+	gl.INT_VEC3:          {12, 1},
+	gl.INT_VEC4:          {16, 1},
+	gl.UNSIGNED_INT:      {4, 1},
+	gl.UNSIGNED_INT_VEC2: {8, 1},
+	gl.UNSIGNED_INT_VEC3: {12, 1},
+	gl.UNSIGNED_INT_VEC4: {16, 1},
+	gl.BOOL:              {4, 1},
+	gl.FLOAT_MAT2:        {0, 2},
+	gl.FLOAT_MAT3:        {0, 3},
+	gl.FLOAT_MAT4:        {0, 4},
+}
+
+// size of buffer variable in bytes
+func (bvi iBufferVariable) Size() (uint32, error) {
+	ty, ok := glslTypes[bvi.Type]
+	if !ok {
+		return 0, fmt.Errorf("unsupported type 0x%x of %v", bvi.Type, bvi.Name)
+	}
+	size := ty.size
+	if ty.columns > 1 {
+		size = ty.columns * bvi.MatrixStride
+	}
+	if bvi.ArraySize > 1 {
+		size = bvi.ArraySize * bvi.ArrayStride
+	}
+	return size, nil
+}
+
+// GLSL member name of Go struct field: `glsl` tag or field name compared
+// ignoring case and underscores, so that `prevF` matches `prev_f`
+func glslName(field reflect.StructField) string {
+	if name, ok := field.Tag.Lookup("glsl"); ok {
+		return name
+	}
+	return field.Name
+}
+
+func normalizeName(name string) string {
+	return strings.ToLower(strings.Replace(name, "_", "", -1))
+}
+
+// Mismatches between Go struct and GLSL struct layout
+type LayoutError struct {
+	Block      string
+	Array      string
+	GoType     reflect.Type
+	Mismatches []string
+}
+
+func (le *LayoutError) Error() string {
+	return fmt.Sprintf("layout of %v does not match %v in SSBO %v:\n - %v",
+		le.GoType, le.Array, le.Block, strings.Join(le.Mismatches, "\n - "))
+}
+
+// Checks that elements of array `arrayName` in SSBO `blockName` have the same
+// offsets, sizes and stride as fields of Go struct `goType`. Blank fields are
+// treated as padding. Returns nil if technique has no such SSBO.
+func ValidateBufferLayout(t *core.Technique, blockName, arrayName string, goType reflect.Type) error {
+	ssbos, err := shaderStorageBuffers(t)
+	if err != nil {
+		return err
+	}
+
+	var ssbo *iShaderStorageBuffer
+	for i := range ssbos {
+		if ssbos[i].Name == blockName {
+			ssbo = &ssbos[i]
+		}
+	}
+	if ssbo == nil {
+		return nil
+	}
+
+	le := LayoutError{Block: blockName, Array: arrayName, GoType: goType}
+
+	goFields := make(map[string]reflect.StructField)
+	for i := 0; i < goType.NumField(); i++ {
+		if field := goType.Field(i); field.Name != "_" {
+			goFields[normalizeName(glslName(field))] = field
+		}
+	}
+
+	strideChecked := false
+	prefix := arrayName + "[0]."
+	for _, variable := range ssbo.Variables {
+		if !strings.HasPrefix(variable.Name, prefix) {
+			continue
+		}
+		member := strings.TrimPrefix(variable.Name, prefix)
+
+		if !strideChecked && uintptr(variable.TopLevelArrayStride) != goType.Size() {
+			le.Mismatches = append(le.Mismatches, fmt.Sprintf("stride is %v, GLSL stride is %v", goType.Size(), variable.TopLevelArrayStride))
+		}
+		strideChecked = true
+
+		field, ok := goFields[normalizeName(member)]
+		if !ok {
+			if member != "_" {
+				le.Mismatches = append(le.Mismatches, fmt.Sprintf("no field for GLSL member %v", member))
+			}
+			continue
+		}
+		delete(goFields, normalizeName(member))
+
+		size, err := variable.Size()
+		if err != nil {
+			return err
+		}
+		if uint32(field.Offset) != variable.Offset {
+			le.Mismatches = append(le.Mismatches, fmt.Sprintf("offset of %v is %v, GLSL offset of %v is %v", field.Name, field.Offset, member, variable.Offset))
+		}
+		if uint32(field.Type.Size()) != size {
+			le.Mismatches = append(le.Mismatches, fmt.Sprintf("size of %v is %v, GLSL size of %v is %v", field.Name, field.Type.Size(), member, size))
+		}
+	}
+
+	for i := 0; i < goType.NumField(); i++ {
+		if field, ok := goFields[normalizeName(glslName(goType.Field(i)))]; ok && field.Index[0] == i {
+			le.Mismatches = append(le.Mismatches, fmt.Sprintf("no GLSL member for field %v", field.Name))
+		}
+	}
+
+	if len(le.Mismatches) > 0 {
+		return &le
+	}
+	return nil
+}
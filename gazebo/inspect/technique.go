@@ -7,60 +7,42 @@ import "github.com/dmarychev/gazebo/core"
 type iUniformVariable struct {
 	Name     string
 	Location uint32
+	Type     uint32
+	Size     int32
 }
 
 func (uvi iUniformVariable) String() string {
-	return fmt.Sprintf("%v(location=%v) uniform variable", uvi.Name, uvi.Location)
+	return fmt.Sprintf("%v(location=%v type=0x%x size=%v) uniform variable", uvi.Name, uvi.Location, uvi.Type, uvi.Size)
 }
 
+// uniforms are enumerated by core at link time, to cache their locations
 func uniformVariables(t *core.Technique) ([]iUniformVariable, error) {
-
-	var numUniforms int32
-	gl.GetProgramInterfaceiv(uint32(*t), gl.UNIFORM, gl.ACTIVE_RESOURCES, &numUniforms)
-	core.CheckError()
-
-	uniformSet := make([]iUniformVariable, 0, numUniforms)
-	if cap(uniformSet) == 0 {
-		return uniformSet, nil
-	}
-
-	for uniformIndex := uint32(0); uniformIndex < uint32(numUniforms); uniformIndex++ {
-
-		// retrieve name length
-		var nameLen int32
-		nameLenProp := uint32(gl.NAME_LENGTH)
-		gl.GetProgramResourceiv(uint32(*t), gl.UNIFORM, uint32(uniformIndex), 1, &nameLenProp, 1, nil, &nameLen)
-		core.CheckError()
-
-		// retrieve name
-		name := make([]uint8, nameLen)
-		gl.GetProgramResourceName(uint32(*t), gl.UNIFORM, uint32(uniformIndex), int32(len(name)), &nameLen, &name[0])
-		name = name[:nameLen]
-		core.CheckError()
-
-		// location
-		var location int32
-		locationProp := uint32(gl.LOCATION)
-		gl.GetProgramResourceiv(uint32(*t), gl.UNIFORM, uint32(uniformIndex), 1, &locationProp, 1, nil, &location)
-		core.CheckError()
-
+	uniforms := t.Uniforms()
+	uniformSet := make([]iUniformVariable, 0, len(uniforms))
+	for _, u := range uniforms {
 		uniformSet = append(uniformSet, iUniformVariable{
-			Name:     string(name),
-			Location: uint32(location),
+			Name:     u.Name,
+			Location: uint32(u.Location),
+			Type:     u.Type,
+			Size:     u.Size,
 		})
 	}
-
 	return uniformSet, nil
 }
 
 type iBufferVariable struct {
-	Name   string
-	Index  uint32
-	Offset uint32
+	Name                string
+	Index               uint32
+	Offset              uint32
+	Type                uint32
+	ArraySize           uint32
+	ArrayStride         uint32
+	MatrixStride        uint32
+	TopLevelArrayStride uint32
 }
 
 func (bvi iBufferVariable) String() string {
-	return fmt.Sprintf("%v(offset=%v) buffer variable", bvi.Name, bvi.Offset)
+	return fmt.Sprintf("%v(offset=%v type=0x%x stride=%v) buffer variable", bvi.Name, bvi.Offset, bvi.Type, bvi.TopLevelArrayStride)
 }
 
 func bufferVariable(t *core.Technique, varIndex uint32) (*iBufferVariable, error) {
@@ -68,25 +50,36 @@ func bufferVariable(t *core.Technique, varIndex uint32) (*iBufferVariable, error
 	// retrieve name length
 	var nameLen int32
 	nameLenProp := uint32(gl.NAME_LENGTH)
-	gl.GetProgramResourceiv(uint32(*t), gl.BUFFER_VARIABLE, uint32(varIndex), 1, &nameLenProp, 1, nil, &nameLen)
-	core.CheckError()
+	gl.GetProgramResourceiv(t.Program(), gl.BUFFER_VARIABLE, uint32(varIndex), 1, &nameLenProp, 1, nil, &nameLen)
+	if err := core.GetError(); err != nil {
+		return nil, err
+	}
 
 	// retrieve name
 	name := make([]uint8, nameLen)
-	gl.GetProgramResourceName(uint32(*t), gl.BUFFER_VARIABLE, uint32(varIndex), int32(len(name)), &nameLen, &name[0])
+	gl.GetProgramResourceName(t.Program(), gl.BUFFER_VARIABLE, uint32(varIndex), int32(len(name)), &nameLen, &name[0])
 	name = name[:nameLen]
-	core.CheckError()
+	if err := core.GetError(); err != nil {
+		return nil, err
+	}
 
-	// retrieve offset
-	var offset int32
-	offsetProp := uint32(gl.OFFSET)
-	gl.GetProgramResourceiv(uint32(*t), gl.BUFFER_VARIABLE, uint32(varIndex), 1, &offsetProp, 1, nil, &offset)
-	core.CheckError()
+	// retrieve layout
+	layoutProps := []uint32{gl.OFFSET, gl.TYPE, gl.ARRAY_SIZE, gl.ARRAY_STRIDE, gl.MATRIX_STRIDE, gl.TOP_LEVEL_ARRAY_STRIDE}
+	layout := make([]int32, len(layoutProps))
+	gl.GetProgramResourceiv(t.Program(), gl.BUFFER_VARIABLE, uint32(varIndex), int32(len(layoutProps)), &layoutProps[0], int32(len(layout)), nil, &layout[0])
+	if err := core.GetError(); err != nil {
+		return nil, err
+	}
 
 	return &iBufferVariable{
-		Index:  varIndex,
-		Name:   string(name),
-		Offset: uint32(offset),
+		Index:               varIndex,
+		Name:                string(name),
+		Offset:              uint32(layout[0]),
+		Type:                uint32(layout[1]),
+		ArraySize:           uint32(layout[2]),
+		ArrayStride:         uint32(layout[3]),
+		MatrixStride:        uint32(layout[4]),
+		TopLevelArrayStride: uint32(layout[5]),
 	}, nil
 }
 
@@ -102,8 +95,10 @@ func (ssbi iShaderStorageBuffer) String() string {
 
 func shaderStorageBuffers(t *core.Technique) ([]iShaderStorageBuffer, error) {
 	var numSsb int32
-	gl.GetProgramInterfaceiv(uint32(*t), gl.SHADER_STORAGE_BLOCK, gl.ACTIVE_RESOURCES, &numSsb)
-	core.CheckError()
+	gl.GetProgramInterfaceiv(t.Program(), gl.SHADER_STORAGE_BLOCK, gl.ACTIVE_RESOURCES, &numSsb)
+	if err := core.GetError(); err != nil {
+		return nil, err
+	}
 
 	ssbiSet := make([]iShaderStorageBuffer, 0, numSsb)
 	if cap(ssbiSet) == 0 {
@@ -115,27 +110,27 @@ func shaderStorageBuffers(t *core.Technique) ([]iShaderStorageBuffer, error) {
 		// retrieve name length
 		var nameLen int32
 		nameLenProp := uint32(gl.NAME_LENGTH)
-		gl.GetProgramResourceiv(uint32(*t), gl.SHADER_STORAGE_BLOCK, ssbIndex, 1, &nameLenProp, 1, nil, &nameLen)
+		gl.GetProgramResourceiv(t.Program(), gl.SHADER_STORAGE_BLOCK, ssbIndex, 1, &nameLenProp, 1, nil, &nameLen)
 
 		// retrieve name
 		name := make([]uint8, nameLen)
-		gl.GetProgramResourceName(uint32(*t), gl.SHADER_STORAGE_BLOCK, ssbIndex, int32(len(name)), &nameLen, &name[0])
+		gl.GetProgramResourceName(t.Program(), gl.SHADER_STORAGE_BLOCK, ssbIndex, int32(len(name)), &nameLen, &name[0])
 		name = name[:nameLen]
 
 		// retrieve binding
 		var binding int32
 		bindingProp := uint32(gl.BUFFER_BINDING)
-		gl.GetProgramResourceiv(uint32(*t), gl.SHADER_STORAGE_BLOCK, ssbIndex, 1, &bindingProp, 1, nil, &binding)
+		gl.GetProgramResourceiv(t.Program(), gl.SHADER_STORAGE_BLOCK, ssbIndex, 1, &bindingProp, 1, nil, &binding)
 
 		// retrieve number of variables
 		var numVariables int32
 		numVariablesProp := uint32(gl.NUM_ACTIVE_VARIABLES)
-		gl.GetProgramResourceiv(uint32(*t), gl.SHADER_STORAGE_BLOCK, ssbIndex, 1, &numVariablesProp, 1, nil, &numVariables)
+		gl.GetProgramResourceiv(t.Program(), gl.SHADER_STORAGE_BLOCK, ssbIndex, 1, &numVariablesProp, 1, nil, &numVariables)
 
 		// retrieve variable indices
 		varIndices := make([]int32, numVariables)
 		varIndicesProp := uint32(gl.ACTIVE_VARIABLES)
-		gl.GetProgramResourceiv(uint32(*t), gl.SHADER_STORAGE_BLOCK, ssbIndex, 1, &varIndicesProp, numVariables, nil, &varIndices[0])
+		gl.GetProgramResourceiv(t.Program(), gl.SHADER_STORAGE_BLOCK, ssbIndex, 1, &varIndicesProp, numVariables, nil, &varIndices[0])
 
 		variableInfos := make([]iBufferVariable, 0, numVariables)
 		for _, varIndex := range varIndices {
@@ -0,0 +1,173 @@
+package particles
+
+import "errors"
+import "fmt"
+import "math"
+import "github.com/dmarychev/gazebo/core"
+
+// behavior of particles crossing wall, must match WALL_* in sph/apply_boundaries.cs
+type WallBehavior int32
+
+const (
+	WALL_REFLECT  WallBehavior = iota // particle is put back inside and its velocity is multiplied by damping
+	WALL_PERIODIC                     // particle enters domain through the opposite wall
+	WALL_OPEN                         // particle leaves domain freely
+)
+
+var wallBehaviorNames = map[string]WallBehavior{
+	"reflect":  WALL_REFLECT,
+	"periodic": WALL_PERIODIC,
+	"open":     WALL_OPEN,
+}
+
+func ParseWallBehavior(name string) (WallBehavior, error) {
+	if wb, ok := wallBehaviorNames[name]; ok {
+		return wb, nil
+	}
+	return 0, fmt.Errorf("unknown wall behavior %q", name)
+}
+
+// walls of domain, lower and upper walls of axis i are 2*i and 2*i+1
+const (
+	WALL_LEFT = iota
+	WALL_RIGHT
+	WALL_BOTTOM
+	WALL_TOP
+	COUNT_WALLS
+)
+
+// walls along z axis of 3D domain, front one is closer to default camera
+const (
+	WALL_BACK = COUNT_WALLS + iota
+	WALL_FRONT
+	COUNT_WALLS3
+)
+
+var wallNames = [COUNT_WALLS3]string{"left", "right", "bottom", "top", "back", "front"}
+
+// Wall of domain
+type Wall struct {
+	Behavior     WallBehavior // what happens with particles crossing wall
+	Damping      float32      // multiplier of velocity of reflected particles, negative to reverse it
+	SceneDamping bool         // Damping is the one of scene parameters, System.SetParameters updates it
+}
+
+// Axis-aligned box of simulation domain. Particles are moved by periodic walls,
+// but neighbor search doesn't wrap around them, so particles interact across
+// periodic walls only after crossing them.
+type Boundaries struct {
+	Min   core.Vec2         // lower left corner
+	Max   core.Vec2         // upper right corner
+	Walls [COUNT_WALLS]Wall // walls in order of WALL_LEFT, WALL_RIGHT, WALL_BOTTOM and WALL_TOP
+}
+
+// box of ±0.8 with open top, reflecting walls reverse velocity multiplied by `damping`
+// of scene parameters
+func DefaultBoundaries(damping float32) Boundaries {
+	reflect := Wall{Behavior: WALL_REFLECT, Damping: damping, SceneDamping: true}
+	return Boundaries{
+		Min:   core.Vec2{X: -0.8, Y: -0.8},
+		Max:   core.Vec2{X: 0.8, Y: 0.8},
+		Walls: [COUNT_WALLS]Wall{reflect, reflect, reflect, {Behavior: WALL_OPEN, Damping: damping, SceneDamping: true}},
+	}
+}
+
+// checks that box is not empty and periodic walls have periodic opposite walls
+func (b *Boundaries) Validate() error {
+	if b.Min.X >= b.Max.X || b.Min.Y >= b.Max.Y {
+		return fmt.Errorf("empty domain from %v to %v", b.Min, b.Max)
+	}
+	return validateWalls(b.Walls[:])
+}
+
+func validateWalls(walls []Wall) error {
+	for wall := 0; wall < len(walls); wall += 2 {
+		lower, upper := walls[wall].Behavior, walls[wall+1].Behavior
+		if (lower == WALL_PERIODIC) != (upper == WALL_PERIODIC) {
+			return fmt.Errorf("periodic %v wall requires periodic %v wall", wallNames[wall], wallNames[wall+1])
+		}
+	}
+	return nil
+}
+
+// sets domain_min, domain_max, wall_behaviors and wall_damping uniforms to technique,
+// uniforms technique doesn't declare are skipped
+func (b *Boundaries) Apply(t *core.Technique) error {
+	return applyWalls(t, b.Walls[:], t.SetUniformVec2("domain_min", b.Min), t.SetUniformVec2("domain_max", b.Max))
+}
+
+// sets wall_behaviors and wall_damping uniforms, `errs` of other uniforms are checked as well
+func applyWalls(t *core.Technique, walls []Wall, errs ...error) error {
+	behaviors := make([]int32, len(walls))
+	damping := make([]float32, len(walls))
+	for i, wall := range walls {
+		behaviors[i], damping[i] = int32(wall.Behavior), wall.Damping
+	}
+
+	for _, err := range append(errs,
+		t.SetUniformIntArray("wall_behaviors", behaviors),
+		t.SetUniformFloat32Array("wall_damping", damping),
+	) {
+		if err != nil && !errors.Is(err, core.ErrUnknownUniform) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Axis-aligned box of 3D simulation domain, see Boundaries
+type Boundaries3 struct {
+	Min   core.Vec3          // corner of left, bottom and back walls
+	Max   core.Vec3          // corner of right, top and front walls
+	Walls [COUNT_WALLS3]Wall // walls in order of WALL_LEFT, WALL_RIGHT, WALL_BOTTOM, WALL_TOP, WALL_BACK and WALL_FRONT
+}
+
+// cube of ±0.8 with open top, reflecting walls reverse velocity multiplied by `damping`
+// of scene parameters
+func DefaultBoundaries3(damping float32) Boundaries3 {
+	reflect := Wall{Behavior: WALL_REFLECT, Damping: damping, SceneDamping: true}
+	return Boundaries3{
+		Min:   core.Vec3{X: -0.8, Y: -0.8, Z: -0.8},
+		Max:   core.Vec3{X: 0.8, Y: 0.8, Z: 0.8},
+		Walls: [COUNT_WALLS3]Wall{reflect, reflect, reflect, {Behavior: WALL_OPEN, Damping: damping, SceneDamping: true}, reflect, reflect},
+	}
+}
+
+// checks that box is not empty and periodic walls have periodic opposite walls
+func (b *Boundaries3) Validate() error {
+	if b.Min.X >= b.Max.X || b.Min.Y >= b.Max.Y || b.Min.Z >= b.Max.Z {
+		return fmt.Errorf("empty domain from %v to %v", b.Min, b.Max)
+	}
+	return validateWalls(b.Walls[:])
+}
+
+// sets domain_min, domain_max, wall_behaviors and wall_damping uniforms of
+// sph3/apply_boundaries.cs to technique, uniforms technique doesn't declare are skipped
+func (b *Boundaries3) Apply(t *core.Technique) error {
+	return applyWalls(t, b.Walls[:], t.SetUniformVec3("domain_min", b.Min), t.SetUniformVec3("domain_max", b.Max))
+}
+
+// applies lower and upper walls along axis to coordinate `r` and velocity `v`
+// of particle, like apply_walls in sph/apply_boundaries.cs
+func (b *Boundaries) apply(axis int, r *float32, v *core.Vec2) {
+	lo, hi := [2]float32{b.Min.X, b.Min.Y}[axis], [2]float32{b.Max.X, b.Max.Y}[axis]
+	var wall Wall
+	var inside float32
+	switch {
+	case *r <= lo:
+		wall, inside = b.Walls[2*axis], lo+BOUNDARY_EPS
+	case *r >= hi:
+		wall, inside = b.Walls[2*axis+1], hi-BOUNDARY_EPS
+	default:
+		return
+	}
+
+	switch wall.Behavior {
+	case WALL_REFLECT:
+		*r = inside
+		*v = core.Vec2{X: v.X * wall.Damping, Y: v.Y * wall.Damping}
+	case WALL_PERIODIC:
+		d, size := *r-lo, hi-lo
+		*r = lo + d - size*float32(math.Floor(float64(d/size)))
+	}
+}
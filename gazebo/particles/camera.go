@@ -0,0 +1,123 @@
+package particles
+
+import "errors"
+import "math"
+import "github.com/dmarychev/gazebo/core"
+
+// Perspective camera 3D particles are rendered with
+type Camera struct {
+	Eye    core.Vec3 // position of camera
+	Center core.Vec3 // point camera looks at
+	Up     core.Vec3 // direction of vertical axis of screen
+	FovY   float32   // vertical field of view in degrees
+	Near   float32   // distance to near clipping plane
+	Far    float32   // distance to far clipping plane
+}
+
+// camera looking at the center of default domain from the front, slightly above and aside
+func DefaultCamera() Camera {
+	return Camera{
+		Eye:    core.Vec3{X: 1.2, Y: 0.9, Z: 2.4},
+		Center: core.Vec3{X: 0, Y: -0.1, Z: 0},
+		Up:     core.Vec3{X: 0, Y: 1, Z: 0},
+		FovY:   45,
+		Near:   0.1,
+		Far:    10,
+	}
+}
+
+// world to camera transformation
+func (c *Camera) View() core.Mat4 {
+	return core.LookAt(c.Eye, c.Center, c.Up)
+}
+
+// camera to clip space transformation for viewport of aspect ratio width/height
+func (c *Camera) Projection(aspect float32) core.Mat4 {
+	return core.Perspective(c.FovY*math.Pi/180, aspect, c.Near, c.Far)
+}
+
+// sets view and projection uniforms for viewport of given size and point_scale,
+// the number of pixels per unit of normalized device coordinates vertically,
+// uniforms technique doesn't declare are skipped
+func (c *Camera) apply(t *core.Technique, width, height int32) error {
+	for _, err := range []error{
+		t.SetUniformMat4("view", c.View()),
+		t.SetUniformMat4("projection", c.Projection(aspectOf(int(width), int(height)))),
+		t.SetUniformFloat32("point_scale", float32(height)/2),
+	} {
+		if err != nil && !errors.Is(err, core.ErrUnknownUniform) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Orthographic camera 2D particles are rendered with, visible region is
+// widened or narrowed to aspect ratio of viewport, so that circles stay round
+type OrthoCamera struct {
+	Center core.Vec2 // point in the center of viewport
+	Height float32   // height of visible region
+}
+
+// camera showing domain [-1, 1] vertically
+func DefaultOrthoCamera() OrthoCamera {
+	return OrthoCamera{Center: core.Vec2{X: 0, Y: 0}, Height: 2}
+}
+
+// world to clip space transformation for viewport of aspect ratio width/height
+func (c *OrthoCamera) Projection(aspect float32) core.Mat4 {
+	hw, hh := c.Height*aspect/2, c.Height/2
+	return core.Orthographic(c.Center.X-hw, c.Center.X+hw, c.Center.Y-hh, c.Center.Y+hh, -1, 1)
+}
+
+// position in window of `width` x `height` with origin in the top left corner
+// of world point `p`, like window coordinates of cursor
+func (c *OrthoCamera) WorldToScreen(p core.Vec2, width, height int) core.Vec2 {
+	ndc := c.Projection(aspectOf(width, height)).MulPoint(core.Vec3{X: p.X, Y: p.Y})
+	return core.Vec2{X: (ndc.X + 1) / 2 * float32(width), Y: (1 - ndc.Y) / 2 * float32(height)}
+}
+
+// world point at position `s` in window of `width` x `height` with origin
+// in the top left corner
+func (c *OrthoCamera) ScreenToWorld(s core.Vec2, width, height int) core.Vec2 {
+	inverse, _ := c.Projection(aspectOf(width, height)).Inverse()
+	ndc := core.Vec3{X: 2*s.X/float32(width) - 1, Y: 1 - 2*s.Y/float32(height)}
+	p := inverse.MulPoint(ndc)
+	return core.Vec2{X: p.X, Y: p.Y}
+}
+
+// moves camera so that content follows cursor moved by `d` pixels in window of `width` x `height`
+func (c *OrthoCamera) Pan(d core.Vec2, width, height int) {
+	if height <= 0 {
+		return
+	}
+	scale := c.Height / float32(height)
+	c.Center = c.Center.Add(core.Vec2{X: -d.X * scale, Y: d.Y * scale})
+}
+
+// scales visible region by `factor`, less than 1 zooms in, keeping world point
+// under position `s` in window of `width` x `height` in place
+func (c *OrthoCamera) Zoom(factor float32, s core.Vec2, width, height int) {
+	if factor <= 0 || width <= 0 || height <= 0 {
+		return
+	}
+	anchor := c.ScreenToWorld(s, width, height)
+	c.Center = anchor.Add(c.Center.Sub(anchor).Scale(factor))
+	c.Height *= factor
+}
+
+// sets projection uniform for viewport of given size, technique may not declare it
+func (c *OrthoCamera) apply(t *core.Technique, width, height int32) error {
+	err := t.SetUniformMat4("projection", c.Projection(aspectOf(int(width), int(height))))
+	if err != nil && !errors.Is(err, core.ErrUnknownUniform) {
+		return err
+	}
+	return nil
+}
+
+func aspectOf(width, height int) float32 {
+	if height <= 0 {
+		return 1
+	}
+	return float32(width) / float32(height)
+}
@@ -0,0 +1,148 @@
+package particles
+
+import "errors"
+import "fmt"
+import "math"
+import "time"
+import "github.com/dmarychev/gazebo/core"
+
+// scalar field of particles mapped to color, must match color_field in vfx/color_field.glsl
+type ColorField int32
+
+const (
+	COLOR_FIELD_NONE     ColorField = iota // constant color
+	COLOR_FIELD_SPEED                      // length of velocity
+	COLOR_FIELD_DENSITY                    // density
+	COLOR_FIELD_PRESSURE                   // pressure
+	COLOR_FIELD_FORCE                      // length of total force
+	COLOR_FIELD_MASS                       // mass
+	COUNT_COLOR_FIELDS
+)
+
+var colorFieldNames = [COUNT_COLOR_FIELDS]string{"none", "speed", "density", "pressure", "force", "mass"}
+
+func (cf ColorField) String() string {
+	if cf < 0 || cf >= COUNT_COLOR_FIELDS {
+		return fmt.Sprintf("ColorField(%d)", int32(cf))
+	}
+	return colorFieldNames[cf]
+}
+
+// value of field for particle, the same as calculated by vfx/color_field.glsl
+func (cf ColorField) value(p Particle) float32 {
+	switch cf {
+	case COLOR_FIELD_SPEED:
+		return p.V.Length()
+	case COLOR_FIELD_DENSITY:
+		return p.D
+	case COLOR_FIELD_PRESSURE:
+		return p.P
+	case COLOR_FIELD_FORCE:
+		return p.F.Length()
+	case COLOR_FIELD_MASS:
+		return p.M
+	}
+	return 0
+}
+
+// value of field for 3D particle, the same as calculated by vfx/color_field.glsl
+func (cf ColorField) value3(p Particle3) float32 {
+	switch cf {
+	case COLOR_FIELD_SPEED:
+		return p.V.Length()
+	case COLOR_FIELD_DENSITY:
+		return p.D
+	case COLOR_FIELD_PRESSURE:
+		return p.P
+	case COLOR_FIELD_FORCE:
+		return p.F.Length()
+	case COLOR_FIELD_MASS:
+		return p.M
+	}
+	return 0
+}
+
+// colormap of scalar field, must match colormap in vfx/colormap.glsl
+type Colormap int32
+
+const (
+	COLORMAP_VIRIDIS  Colormap = iota // perceptually uniform, dark blue to yellow
+	COLORMAP_COOLWARM                 // diverging, blue to red
+	COUNT_COLORMAPS
+)
+
+var colormapNames = [COUNT_COLORMAPS]string{"viridis", "coolwarm"}
+
+func (cm Colormap) String() string {
+	if cm < 0 || cm >= COUNT_COLORMAPS {
+		return fmt.Sprintf("Colormap(%d)", int32(cm))
+	}
+	return colormapNames[cm]
+}
+
+func ParseColorField(name string) (ColorField, error) {
+	for i, n := range colorFieldNames {
+		if n == name {
+			return ColorField(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown color field %q", name)
+}
+
+func ParseColormap(name string) (Colormap, error) {
+	for i, n := range colormapNames {
+		if n == name {
+			return Colormap(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown colormap %q", name)
+}
+
+const (
+	AUTO_RANGE_INTERVAL = time.Second // how often automatic range of coloring is recalculated
+)
+
+// Coloring of particles by point renderer. Field values in Range are mapped
+// to colormap linearly, values outside are clamped. With AutoRange the range
+// is taken from minimal and maximal values when coloring is set and then every
+// AUTO_RANGE_INTERVAL, which requires reading particles back from GPU.
+type Coloring struct {
+	Field     ColorField // field mapped to color
+	Map       Colormap   // colormap
+	Range     core.Vec2  // minimal and maximal values of field
+	AutoRange bool       // calculate range from particles every AUTO_RANGE_INTERVAL
+}
+
+// range of field values of `count` particles, degenerate range is widened to avoid division by zero
+func (c *Coloring) autoRange(count int, fieldValue func(i int) float32) core.Vec2 {
+	r := core.Vec2{X: float32(math.Inf(1)), Y: float32(math.Inf(-1))}
+	for i := 0; i < count; i++ {
+		value := fieldValue(i)
+		r.X = float32(math.Min(float64(r.X), float64(value)))
+		r.Y = float32(math.Max(float64(r.Y), float64(value)))
+	}
+	if count == 0 {
+		r = core.Vec2{}
+	}
+	if r.Y <= r.X {
+		r.Y = r.X + 1
+	}
+	return r
+}
+
+// sets uniforms of coloring to technique, uniforms technique doesn't declare are skipped
+func (c *Coloring) apply(t *core.Technique, valueRange core.Vec2) error {
+	set := func(err error) error {
+		if errors.Is(err, core.ErrUnknownUniform) {
+			return nil
+		}
+		return err
+	}
+	if err := set(t.SetUniformInt("color_field", int32(c.Field))); err != nil {
+		return err
+	}
+	if err := set(t.SetUniformInt("colormap", int32(c.Map))); err != nil {
+		return err
+	}
+	return set(t.SetUniformVec2("value_range", valueRange))
+}
@@ -0,0 +1,203 @@
+package particles
+
+import "fmt"
+import "math"
+import "github.com/dmarychev/gazebo/core"
+
+const (
+	BOUNDARY_EPS = 0.001 // distance from reflecting wall particles are put at, must match sph/apply_boundaries.cs
+)
+
+// Reference implementation of SPH pipeline on CPU, every stage mirrors compute shader
+// of the same name in sph/ using single precision, so GPU results can be checked against
+// it within rounding errors. Neighbors are stored in index of the same layout as on GPU.
+type CPUSolver struct {
+	Parameters SceneParameters              // physical parameters, the same as uniforms of shaders
+	Boundaries Boundaries                   // domain of apply_boundaries stage
+	Obstacles  []Obstacle                   // obstacles of collide_obstacles stage
+	stages     []func(particles []Particle) // stages run after neighbor search
+	index      []uint32                     // MaxNeighbors slots per particle, terminated by INDEX_EMPTY_SLOT
+}
+
+// creates solver running given stages after neighbor search, stages are named
+// after shaders: "density_and_pressure", "accumulate_forces", "leapfrog_integration"
+// "apply_boundaries" and "collide_obstacles"
+func NewCPUSolver(parameters SceneParameters, boundaries Boundaries, stages []string) (*CPUSolver, error) {
+	cs := CPUSolver{Parameters: parameters, Boundaries: boundaries}
+	for _, stage := range stages {
+		switch stage {
+		case "density_and_pressure":
+			cs.stages = append(cs.stages, cs.DensityAndPressure)
+		case "accumulate_forces":
+			cs.stages = append(cs.stages, cs.AccumulateForces)
+		case "leapfrog_integration":
+			cs.stages = append(cs.stages, cs.LeapfrogIntegration)
+		case "apply_boundaries":
+			cs.stages = append(cs.stages, cs.ApplyBoundaries)
+		case "collide_obstacles":
+			cs.stages = append(cs.stages, cs.CollideObstacles)
+		default:
+			return nil, fmt.Errorf("no CPU implementation of stage %q", stage)
+		}
+	}
+	return &cs, nil
+}
+
+// updates neighbor index and runs all stages
+func (cs *CPUSolver) Step(particles []Particle) {
+	cs.UpdateIndex(particles)
+	for _, stage := range cs.stages {
+		stage(particles)
+	}
+}
+
+// neighbors index of the last UpdateIndex
+func (cs *CPUSolver) Index() []uint32 {
+	return cs.index
+}
+
+// fills index with particles closer than h, including particle itself,
+// like index_update.cs and grid_neighbors.cs
+func (cs *CPUSolver) UpdateIndex(particles []Particle) {
+	h := cs.Parameters.SmoothingRadius
+	maxNeighbors := int(cs.Parameters.MaxNeighbors)
+
+	cellOf := func(r core.Vec2) [2]int32 {
+		return [2]int32{int32(math.Floor(float64(r.X / h))), int32(math.Floor(float64(r.Y / h)))}
+	}
+	cells := make(map[[2]int32][]uint32)
+	for i := range particles {
+		c := cellOf(particles[i].R)
+		cells[c] = append(cells[c], uint32(i))
+	}
+
+	if len(cs.index) != len(particles)*maxNeighbors {
+		cs.index = make([]uint32, len(particles)*maxNeighbors)
+	}
+
+	for i := range particles {
+		p := particles[i]
+		c := cellOf(p.R)
+		neighbors := cs.index[i*maxNeighbors : (i+1)*maxNeighbors]
+		count := 0
+		for dy := int32(-1); dy <= 1; dy++ {
+			for dx := int32(-1); dx <= 1; dx++ {
+				for _, candidate := range cells[[2]int32{c[0] + dx, c[1] + dy}] {
+					d := core.Vec2{X: p.R.X - particles[candidate].R.X, Y: p.R.Y - particles[candidate].R.Y}
+					if count < maxNeighbors && d.Length() < h {
+						neighbors[count] = candidate
+						count++
+					}
+				}
+			}
+		}
+		if count < maxNeighbors {
+			neighbors[count] = INDEX_EMPTY_SLOT
+		}
+	}
+}
+
+// calls `f` for every neighbor of particle `i` in index
+func (cs *CPUSolver) forNeighbors(i int, f func(j uint32)) {
+	maxNeighbors := int(cs.Parameters.MaxNeighbors)
+	for _, j := range cs.index[i*maxNeighbors : (i+1)*maxNeighbors] {
+		if j == INDEX_EMPTY_SLOT {
+			break
+		}
+		f(j)
+	}
+}
+
+// density_and_pressure.cs
+func (cs *CPUSolver) DensityAndPressure(particles []Particle) {
+	h := cs.Parameters.SmoothingRadius
+	poly6Coeff := float32(315.0 / (64.0 * math.Pi * math.Pow(float64(h), 9)))
+	h2 := h * h
+
+	for i := range particles {
+		p := &particles[i]
+		p.D = 0
+		cs.forNeighbors(i, func(j uint32) {
+			o := particles[j]
+			dr := core.Vec2{X: p.R.X - o.R.X, Y: p.R.Y - o.R.Y}
+			dh2dr2 := h2 - (dr.X*dr.X + dr.Y*dr.Y)
+			p.D += o.M * poly6Coeff * dh2dr2 * dh2dr2 * dh2dr2
+		})
+		p.P = cs.Parameters.PressureCoefficient * p.D
+	}
+}
+
+// accumulate_forces.cs, reads densities and pressures of all particles,
+// so forces are written only after all of them are calculated
+func (cs *CPUSolver) AccumulateForces(particles []Particle) {
+	h := cs.Parameters.SmoothingRadius
+	h6 := h * h * h * h * h * h
+	gradCoeff := float32(-45.0 / math.Pi / float64(h6))
+	lapCoeff := float32(45.0 / math.Pi / float64(h6))
+
+	forces := make([]core.Vec2, len(particles))
+	for i := range particles {
+		p := particles[i]
+		var fPress, fVis core.Vec2
+		cs.forNeighbors(i, func(j uint32) {
+			if int(j) == i {
+				return
+			}
+			o := particles[j]
+			dr := core.Vec2{X: p.R.X - o.R.X, Y: p.R.Y - o.R.Y}
+			ldr := dr.Length()
+			var ndr core.Vec2
+			if ldr > 0 {
+				ndr = core.Vec2{X: dr.X / ldr, Y: dr.Y / ldr}
+			}
+
+			press := -(o.M / o.D) * 0.5 * (o.P + p.P) * gradCoeff * (h - ldr) * (h - ldr)
+			fPress.X += press * ndr.X
+			fPress.Y += press * ndr.Y
+
+			vis := (o.M / o.D) * lapCoeff * (h - ldr)
+			fVis.X += vis * (o.V.X - p.V.X)
+			fVis.Y += vis * (o.V.Y - p.V.Y)
+		})
+
+		mu := cs.Parameters.Viscosity
+		forces[i] = core.Vec2{
+			X: fPress.X + mu*fVis.X,
+			Y: fPress.Y - p.D*cs.Parameters.Gravity + mu*fVis.Y,
+		}
+	}
+
+	for i := range particles {
+		particles[i].F = forces[i]
+	}
+}
+
+// leapfrog_integration.cs
+func (cs *CPUSolver) LeapfrogIntegration(particles []Particle) {
+	dt := cs.Parameters.TimeStep
+	for i := range particles {
+		p := &particles[i]
+		vHalf := core.Vec2{X: p.V.X + 0.5*dt*p.prevF.X/p.D, Y: p.V.Y + 0.5*dt*p.prevF.Y/p.D}
+		p.R = core.Vec2{X: p.R.X + vHalf.X*dt, Y: p.R.Y + vHalf.Y*dt}
+		p.V = core.Vec2{X: vHalf.X + 0.5*dt*p.F.X/p.D, Y: vHalf.Y + 0.5*dt*p.F.Y/p.D}
+		p.prevF = p.F
+	}
+}
+
+// apply_boundaries.cs
+func (cs *CPUSolver) ApplyBoundaries(particles []Particle) {
+	for i := range particles {
+		p := &particles[i]
+		cs.Boundaries.apply(0, &p.R.X, &p.V)
+		cs.Boundaries.apply(1, &p.R.Y, &p.V)
+	}
+}
+
+// collide_obstacles.cs
+func (cs *CPUSolver) CollideObstacles(particles []Particle) {
+	for i := range particles {
+		for j := range cs.Obstacles {
+			cs.Obstacles[j].collide(&particles[i])
+		}
+	}
+}
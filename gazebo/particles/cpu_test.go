@@ -0,0 +1,180 @@
+package particles
+
+import "math"
+import "sort"
+import "testing"
+import "unsafe"
+import "github.com/go-gl/gl/v4.6-core/gl"
+import "github.com/dmarychev/gazebo/core"
+
+const (
+	ORACLE_STEPS     = 10   // steps GPU and CPU solutions are compared after
+	ORACLE_TOLERANCE = 1e-3 // relative tolerance of particles' fields
+)
+
+// block of particles with spacing far enough from smoothing radius for neighbor
+// sets not to depend on rounding of distances
+var oracleBlock = Emitter{
+	Type:    "block",
+	Origin:  core.Vec3{X: -0.5, Y: -0.5},
+	Columns: 24,
+	Rows:    24,
+	Spacing: 0.006,
+	Jitter:  0.0002,
+	Mass:    0.004,
+}
+
+// brute force neighbor search of the dam break scene's stages
+func useBruteForce(sc *Scene) {
+	sc.NeighborSearch = NeighborSearchDesc{
+		Method: "brute_force",
+		Shaders: map[string]string{
+			"update": "../sph/index_update.cs",
+			"clear":  "../sph/index_clear.cs",
+		},
+	}
+}
+
+// solver of the same stages and boundaries as scene's pipeline
+func newTestSolver(t *testing.T, sc *Scene) *CPUSolver {
+	t.Helper()
+	sc.Backend = "cpu"
+	s, err := sc.NewSystem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	solver := s.solver
+	s.Close()
+	sc.Backend = ""
+	return solver
+}
+
+// true if `a` and `b` differ by at most ORACLE_TOLERANCE relative to `scale`,
+// the largest magnitude of the field, so that values close to zero are compared too
+func within(a, b, scale float32) bool {
+	return math.Abs(float64(a-b)) <= ORACLE_TOLERANCE*float64(scale)
+}
+
+func maxAbs(particles []Particle, field func(p *Particle) float32) float32 {
+	m := float32(0)
+	for i := range particles {
+		m = float32(math.Max(float64(m), math.Abs(float64(field(&particles[i])))))
+	}
+	return m
+}
+
+func TestCPUSolverMatchesGPU(t *testing.T) {
+	withContext(t)
+	sc := loadTestScene(t, oracleBlock)
+	solver := newTestSolver(t, sc)
+	s, initial := newTestSystem(t, sc)
+
+	expected := append([]Particle(nil), initial...)
+	for step := 0; step < ORACLE_STEPS; step++ {
+		if err := s.Update(); err != nil {
+			t.Fatal(err)
+		}
+		solver.Step(expected)
+	}
+	actual, err := s.Particles()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fields := []struct {
+		name  string
+		value func(p *Particle) float32
+	}{
+		{"R.X", func(p *Particle) float32 { return p.R.X }},
+		{"R.Y", func(p *Particle) float32 { return p.R.Y }},
+		{"V.X", func(p *Particle) float32 { return p.V.X }},
+		{"V.Y", func(p *Particle) float32 { return p.V.Y }},
+		{"D", func(p *Particle) float32 { return p.D }},
+		{"P", func(p *Particle) float32 { return p.P }},
+	}
+	for _, field := range fields {
+		scale := maxAbs(expected, field.value)
+		mismatches := 0
+		for i := range expected {
+			a, e := field.value(&actual[i]), field.value(&expected[i])
+			if !within(a, e, scale) {
+				if mismatches < 5 {
+					t.Errorf("%v of particle %v is %v on GPU, %v on CPU", field.name, i, a, e)
+				}
+				mismatches++
+			}
+		}
+		if mismatches > 0 {
+			t.Errorf("%v of %v particles differ", field.name, mismatches)
+		}
+	}
+}
+
+// neighbors of every particle in index, sorted
+func neighborSets(index []uint32, countParticles, maxNeighbors int) [][]uint32 {
+	sets := make([][]uint32, countParticles)
+	for i := range sets {
+		for _, j := range index[i*maxNeighbors : (i+1)*maxNeighbors] {
+			if j == INDEX_EMPTY_SLOT {
+				break
+			}
+			sets[i] = append(sets[i], j)
+		}
+		sort.Slice(sets[i], func(a, b int) bool { return sets[i][a] < sets[i][b] })
+	}
+	return sets
+}
+
+func TestCPUSolverIndexMatchesGPU(t *testing.T) {
+	withContext(t)
+	// subtests run in other goroutines, which have no context
+	for _, method := range []string{"grid", "brute_force"} {
+		sc := loadTestScene(t, oracleBlock)
+		if method == "brute_force" {
+			useBruteForce(sc)
+		}
+		solver := newTestSolver(t, sc)
+		s, initial := newTestSystem(t, sc)
+
+		// index is updated from particles before the first stage
+		if err := s.Update(); err != nil {
+			t.Fatal(err)
+		}
+		maxNeighbors := int(sc.Parameters.MaxNeighbors)
+		index := make([]uint32, len(initial)*maxNeighbors)
+		if err := s.renderState.indexVbo.GetData(gl.Ptr(index), uint32(len(index))*uint32(unsafe.Sizeof(uint32(0)))); err != nil {
+			t.Fatal(err)
+		}
+		solver.UpdateIndex(initial)
+
+		actual := neighborSets(index, len(initial), maxNeighbors)
+		expected := neighborSets(solver.Index(), len(initial), maxNeighbors)
+		mismatches := 0
+		for i := range expected {
+			if len(actual[i]) == 0 || len(actual[i]) == maxNeighbors {
+				t.Fatalf("%v: particle %v has %v neighbors, expected block to have between 1 and %v", method, i, len(actual[i]), maxNeighbors-1)
+			}
+			if !equalSets(actual[i], expected[i]) {
+				if mismatches < 5 {
+					t.Errorf("%v: neighbors of particle %v are %v on GPU, %v on CPU", method, i, actual[i], expected[i])
+				}
+				mismatches++
+			}
+		}
+		if mismatches > 0 {
+			t.Errorf("%v: neighbors of %v particles differ", method, mismatches)
+		}
+	}
+}
+
+func equalSets(a, b []uint32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
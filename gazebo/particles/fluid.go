@@ -0,0 +1,225 @@
+package particles
+
+import "github.com/go-gl/gl/v4.6-core/gl"
+import "github.com/dmarychev/gazebo/core"
+
+// Screen-space fluid surface renderer. Particles are splatted as spheres into offscreen
+// depth (max of heights) and thickness (sum of heights) targets, depth is smoothed by
+// bilateral blur and the surface is shaded into framebuffer bound when Render is called.
+type FluidRenderer struct {
+	splat          *core.Technique           // renders particles' spheres heights
+	blur           *core.Technique           // blurs depth along one direction
+	shade          *core.Technique           // reconstructs normals and shades surface
+	ParticleRadius float32                   // radius of particle's sphere
+	BlurRadius     int32                     // radius of blur filter in pixels
+	width, height  int32                     // size of offscreen targets
+	depth          [3]core.Texture2D         // depth, depth blurred horizontally and in both directions
+	depthFbo       [3]core.FramebufferObject // framebuffers rendering to depth textures
+	thickness      core.Texture2D            // thickness of fluid
+	thicknessFbo   core.FramebufferObject    // framebuffer rendering to thickness texture
+	screenVao      core.VertexArrayObject    // empty VAO for fullscreen triangle
+}
+
+func NewFluidRenderer(splat, blur, shade *core.Technique) *FluidRenderer {
+	return &FluidRenderer{
+		splat:          splat,
+		blur:           blur,
+		shade:          shade,
+		ParticleRadius: 0.01,
+		BlurRadius:     16,
+		screenVao:      core.MakeVertexArrayObject(),
+	}
+}
+
+// techniques of all passes
+func (fr *FluidRenderer) Techniques() []*core.Technique {
+	return []*core.Technique{fr.splat, fr.blur, fr.shade}
+}
+
+// deletes techniques and offscreen targets
+func (fr *FluidRenderer) Delete() {
+	for _, t := range fr.Techniques() {
+		deleteTechnique(t)
+	}
+	fr.deleteTargets()
+	fr.screenVao.Delete()
+}
+
+// deletes offscreen targets, including partially created ones
+func (fr *FluidRenderer) deleteTargets() {
+	for i := range fr.depth {
+		fr.depthFbo[i].Delete()
+		fr.depth[i].Delete()
+	}
+	fr.thicknessFbo.Delete()
+	fr.thickness.Delete()
+	fr.depth, fr.depthFbo = [3]core.Texture2D{}, [3]core.FramebufferObject{}
+	fr.thickness, fr.thicknessFbo = 0, 0
+	fr.width, fr.height = 0, 0
+}
+
+func makeTarget(width, height int32) (core.Texture2D, core.FramebufferObject, error) {
+	tex, err := core.MakeTexture2D(width, height, gl.R32F)
+	if err != nil {
+		tex.Delete()
+		return 0, 0, err
+	}
+	fbo := core.MakeFramebufferObject()
+	if err = fbo.AttachTexture(gl.COLOR_ATTACHMENT0, tex); err == nil {
+		err = fbo.CheckStatus()
+	}
+	if err != nil {
+		fbo.Delete()
+		tex.Delete()
+		return 0, 0, err
+	}
+	return tex, fbo, nil
+}
+
+// (re)creates offscreen targets of given size
+func (fr *FluidRenderer) resize(width, height int32) error {
+	if width == fr.width && height == fr.height {
+		return nil
+	}
+
+	fr.deleteTargets()
+
+	var err error
+	for i := range fr.depth {
+		if fr.depth[i], fr.depthFbo[i], err = makeTarget(width, height); err != nil {
+			return err
+		}
+	}
+	if fr.thickness, fr.thicknessFbo, err = makeTarget(width, height); err != nil {
+		return err
+	}
+
+	fr.width, fr.height = width, height
+	return nil
+}
+
+// draws fullscreen triangle with technique, textures are bound to units in order
+func (fr *FluidRenderer) drawScreen(t *core.Technique, textures ...core.Texture2D) error {
+	for unit, tex := range textures {
+		unbind := tex.Bind(uint32(unit))
+		defer unbind()
+	}
+
+	unbind := fr.screenVao.Bind()
+	defer unbind()
+
+	disable, err := t.Enable()
+	if err != nil {
+		return err
+	}
+	defer disable()
+
+	gl.DrawArrays(gl.TRIANGLES, 0, 3)
+	return core.GetError()
+}
+
+// renders fluid surface of particles in render state into currently bound framebuffer and viewport,
+// framebuffer, viewport and blending are restored afterwards, also on error
+func (fr *FluidRenderer) Render(rs *RenderState) error {
+	var viewport [4]int32
+	var target int32
+	var blendFunc [4]int32
+	var blendEquation [2]int32
+	gl.GetIntegerv(gl.VIEWPORT, &viewport[0])
+	gl.GetIntegerv(gl.DRAW_FRAMEBUFFER_BINDING, &target)
+	gl.GetIntegerv(gl.BLEND_SRC_RGB, &blendFunc[0])
+	gl.GetIntegerv(gl.BLEND_DST_RGB, &blendFunc[1])
+	gl.GetIntegerv(gl.BLEND_SRC_ALPHA, &blendFunc[2])
+	gl.GetIntegerv(gl.BLEND_DST_ALPHA, &blendFunc[3])
+	gl.GetIntegerv(gl.BLEND_EQUATION_RGB, &blendEquation[0])
+	gl.GetIntegerv(gl.BLEND_EQUATION_ALPHA, &blendEquation[1])
+	blend := gl.IsEnabled(gl.BLEND)
+	defer func() {
+		gl.BindFramebuffer(gl.FRAMEBUFFER, uint32(target))
+		gl.Viewport(viewport[0], viewport[1], viewport[2], viewport[3])
+		gl.BlendFuncSeparate(uint32(blendFunc[0]), uint32(blendFunc[1]), uint32(blendFunc[2]), uint32(blendFunc[3]))
+		gl.BlendEquationSeparate(uint32(blendEquation[0]), uint32(blendEquation[1]))
+		if blend {
+			gl.Enable(gl.BLEND)
+		} else {
+			gl.Disable(gl.BLEND)
+		}
+	}()
+	width, height := viewport[2], viewport[3]
+
+	if err := fr.resize(width, height); err != nil {
+		return err
+	}
+
+	// uniforms depending on viewport
+	if err := fr.splat.SetUniformFloat32("particle_radius", fr.ParticleRadius); err != nil {
+		return err
+	}
+	if err := fr.splat.SetUniformVec2("point_scale", core.Vec2{X: float32(width) / 2, Y: float32(height) / 2}); err != nil {
+		return err
+	}
+	projection := rs.orthoCamera.Projection(aspectOf(int(width), int(height)))
+	texelSize := core.Vec2{X: 2 / (float32(width) * projection.At(0, 0)), Y: 2 / (float32(height) * projection.At(1, 1))}
+	if err := fr.shade.SetUniformVec2("texel_size", texelSize); err != nil {
+		return err
+	}
+	if err := fr.blur.SetUniformInt("blur_radius", fr.BlurRadius); err != nil {
+		return err
+	}
+	if err := rs.orthoCamera.apply(fr.splat, width, height); err != nil {
+		return err
+	}
+
+	gl.Viewport(0, 0, width, height)
+	gl.Enable(gl.BLEND)
+	gl.BlendFunc(gl.ONE, gl.ONE)
+
+	// depth is the maximal height, thickness is the sum of heights
+	passes := []struct {
+		fbo      core.FramebufferObject
+		equation uint32
+	}{
+		{fr.depthFbo[0], gl.MAX},
+		{fr.thicknessFbo, gl.FUNC_ADD},
+	}
+	for _, pass := range passes {
+		unbind := pass.fbo.Bind()
+		zero := [4]float32{}
+		gl.ClearBufferfv(gl.COLOR, 0, &zero[0])
+		gl.BlendEquation(pass.equation)
+		err := rs.drawPoints(fr.splat)
+		unbind()
+		if err != nil {
+			return err
+		}
+	}
+	gl.BlendEquation(gl.FUNC_ADD)
+	gl.Disable(gl.BLEND)
+
+	// separable blur: depth[0] -> depth[1] horizontally, depth[1] -> depth[2] vertically
+	for i, direction := range []core.IVec2{{X: 1, Y: 0}, {X: 0, Y: 1}} {
+		if err := fr.blur.SetUniformIVec2("direction", direction); err != nil {
+			return err
+		}
+		unbind := fr.depthFbo[i+1].Bind()
+		err := fr.drawScreen(fr.blur, fr.depth[i])
+		unbind()
+		if err != nil {
+			return err
+		}
+	}
+
+	// shade into original framebuffer over its content, keeping its alpha
+	core.FramebufferObject(target).Bind()
+	gl.Viewport(viewport[0], viewport[1], viewport[2], viewport[3])
+	gl.Enable(gl.BLEND)
+	gl.BlendFuncSeparate(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA, gl.ZERO, gl.ONE)
+
+	if err := fr.shade.SetUniformInt("depth_texture", 0); err != nil {
+		return err
+	}
+	if err := fr.shade.SetUniformInt("thickness_texture", 1); err != nil {
+		return err
+	}
+	return fr.drawScreen(fr.shade, fr.depth[2], fr.thickness)
+}
@@ -0,0 +1,101 @@
+package particles
+
+import "testing"
+import "github.com/go-gl/gl/v4.6-core/gl"
+import "github.com/dmarychev/gazebo/core"
+
+// has texel_size of shading technique but no textures
+const BROKEN_SHADE_SHADER = `#version 460
+layout(local_size_x = 1) in;
+layout(std430, binding = 0) buffer Out { vec2 result; };
+uniform vec2 texel_size;
+void main() {
+    result = texel_size;
+}
+`
+
+// state Render changes and must restore
+type glState struct {
+	framebuffer   int32
+	viewport      [4]int32
+	blendFunc     [4]int32
+	blendEquation [2]int32
+	blend         bool
+}
+
+func readGLState() glState {
+	var st glState
+	gl.GetIntegerv(gl.DRAW_FRAMEBUFFER_BINDING, &st.framebuffer)
+	gl.GetIntegerv(gl.VIEWPORT, &st.viewport[0])
+	gl.GetIntegerv(gl.BLEND_SRC_RGB, &st.blendFunc[0])
+	gl.GetIntegerv(gl.BLEND_DST_RGB, &st.blendFunc[1])
+	gl.GetIntegerv(gl.BLEND_SRC_ALPHA, &st.blendFunc[2])
+	gl.GetIntegerv(gl.BLEND_DST_ALPHA, &st.blendFunc[3])
+	gl.GetIntegerv(gl.BLEND_EQUATION_RGB, &st.blendEquation[0])
+	gl.GetIntegerv(gl.BLEND_EQUATION_ALPHA, &st.blendEquation[1])
+	st.blend = gl.IsEnabled(gl.BLEND)
+	return st
+}
+
+func TestFluidRenderRestoresState(t *testing.T) {
+	withContext(t)
+	sc := loadTestScene(t, oracleBlock)
+	scene, err := LoadScene("../scenes/dam_break.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc.Render.Fluid = scene.Render.Fluid
+	s, _ := newTestSystem(t, sc)
+	fr := s.renderState.fluidRenderer
+	if fr == nil {
+		t.Fatal("scene has no fluid renderer")
+	}
+
+	color, err := core.MakeTexture2D(64, 64, gl.RGBA8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer color.Delete()
+	fbo := core.MakeFramebufferObject()
+	defer fbo.Delete()
+	if err := fbo.AttachTexture(gl.COLOR_ATTACHMENT0, color); err != nil {
+		t.Fatal(err)
+	}
+
+	// state unlike anything Render sets
+	fbo.Bind()
+	defer gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+	gl.Viewport(3, 5, 40, 30)
+	gl.BlendFuncSeparate(gl.DST_COLOR, gl.ZERO, gl.ONE, gl.SRC_ALPHA)
+	gl.BlendEquationSeparate(gl.FUNC_SUBTRACT, gl.MIN)
+	gl.Enable(gl.BLEND)
+	want := readGLState()
+
+	if err := fr.Render(s.renderState); err != nil {
+		t.Fatal(err)
+	}
+	if got := readGLState(); got != want {
+		t.Errorf("after rendering state is %+v, want %+v", got, want)
+	}
+
+	// shading technique without textures fails after blending is set up for shading
+	src := core.ComputeShaderSource(BROKEN_SHADE_SHADER)
+	broken, err := core.NewComputeTechnique(&src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shade := fr.shade
+	fr.shade = broken
+	defer func() {
+		fr.shade = shade
+		broken.Delete()
+	}()
+	gl.Disable(gl.BLEND)
+	want = readGLState()
+	if err := fr.Render(s.renderState); err == nil {
+		t.Fatal("no error")
+	}
+	if got := readGLState(); got != want {
+		t.Errorf("after failure state is %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,115 @@
+package particles
+
+import "fmt"
+import "reflect"
+import "unsafe"
+import "github.com/go-gl/gl/v4.6-core/gl"
+import "github.com/dmarychev/gazebo/core"
+import "github.com/dmarychev/gazebo/inspect"
+
+// 3D particle struct, every vec3 is followed by scalar occupying its padding,
+// so that layout matches std430 one without padding fields
+type Particle3 struct {
+	R     core.Vec3 // coordinates
+	P     float32   // pressure
+	V     core.Vec3 // velocity
+	D     float32   // density
+	F     core.Vec3 // F(total)
+	M     float32   // mass
+	prevF core.Vec3 // F(total) on previous step
+	_     float32
+}
+
+// vertex attribute of particle field
+type vertexAttribute struct {
+	index  uint32  // ATTRIB_*
+	size   int32   // number of components
+	offset uintptr // offset of field in particle struct
+}
+
+// layout of particles in SSBO "Particles" and vertex buffer of 2D or 3D system
+type particleLayout struct {
+	dimensions int               // 2 or 3
+	goType     reflect.Type      // Particle or Particle3
+	attributes []vertexAttribute // vertex attributes of fields
+}
+
+var layout2D = particleLayout{
+	dimensions: 2,
+	goType:     reflect.TypeOf(Particle{}),
+	attributes: []vertexAttribute{
+		{ATTRIB_COORDINATES, 2, unsafe.Offsetof(Particle{}.R)},
+		{ATTRIB_VELOCITY, 2, unsafe.Offsetof(Particle{}.V)},
+		{ATTRIB_FORCE, 2, unsafe.Offsetof(Particle{}.F)},
+		{ATTRIB_PRESSURE, 1, unsafe.Offsetof(Particle{}.P)},
+		{ATTRIB_DENSITY, 1, unsafe.Offsetof(Particle{}.D)},
+		{ATTRIB_MASS, 1, unsafe.Offsetof(Particle{}.M)},
+	},
+}
+
+var layout3D = particleLayout{
+	dimensions: 3,
+	goType:     reflect.TypeOf(Particle3{}),
+	attributes: []vertexAttribute{
+		{ATTRIB_COORDINATES, 3, unsafe.Offsetof(Particle3{}.R)},
+		{ATTRIB_VELOCITY, 3, unsafe.Offsetof(Particle3{}.V)},
+		{ATTRIB_FORCE, 3, unsafe.Offsetof(Particle3{}.F)},
+		{ATTRIB_PRESSURE, 1, unsafe.Offsetof(Particle3{}.P)},
+		{ATTRIB_DENSITY, 1, unsafe.Offsetof(Particle3{}.D)},
+		{ATTRIB_MASS, 1, unsafe.Offsetof(Particle3{}.M)},
+	},
+}
+
+func particleLayoutOf(dimensions int) (*particleLayout, error) {
+	switch dimensions {
+	case 2:
+		return &layout2D, nil
+	case 3:
+		return &layout3D, nil
+	}
+	return nil, fmt.Errorf("particles can be 2D or 3D, got %v dimensions", dimensions)
+}
+
+// size of particle in bytes
+func (pl *particleLayout) size() uint32 {
+	return uint32(pl.goType.Size())
+}
+
+// binds fields of particles in currently bound array buffer to vertex attributes
+func (pl *particleLayout) attachVertexAttributes() func() {
+	for _, attrib := range pl.attributes {
+		gl.EnableVertexAttribArray(attrib.index)
+		gl.VertexAttribPointer(attrib.index, attrib.size, gl.FLOAT, false, int32(pl.size()), gl.PtrOffset(int(attrib.offset)))
+	}
+	return func() {
+		for _, attrib := range pl.attributes {
+			gl.DisableVertexAttribArray(attrib.index)
+		}
+	}
+}
+
+// checks that Particle3 matches std430 layout of particles in SSBO "Particles"
+// declared by technique, techniques without it are accepted as is
+func ValidateParticle3Layout(t *core.Technique) error {
+	return inspect.ValidateBufferLayout(t, "Particles", "current_particles", reflect.TypeOf(Particle3{}))
+}
+
+// dimensions of particles in SSBO "Particles" declared by technique told by their
+// stride, 0 if technique doesn't access particles, 2 if stride is unknown, so that
+// validation against Particle reports mismatches
+func particleDimensions(t *core.Technique) (int, error) {
+	tinfo, err := inspect.InspectTechnique(t)
+	if err != nil {
+		return 0, err
+	}
+	for _, ssbi := range tinfo.ShaderStorageBuffers {
+		if ssbi.Name != "Particles" || len(ssbi.Variables) == 0 {
+			continue
+		}
+		if uintptr(ssbi.Variables[0].TopLevelArrayStride) == layout3D.goType.Size() {
+			return 3, nil
+		}
+		return 2, nil
+	}
+	return 0, nil
+}
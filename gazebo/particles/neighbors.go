@@ -0,0 +1,177 @@
+package particles
+
+import "unsafe"
+import "github.com/go-gl/gl/v4.6-core/gl"
+import "github.com/dmarychev/gazebo/core"
+
+const (
+	INDEX_EMPTY_SLOT = 0xdeadbeef // terminates list of neighbors in index
+)
+
+// SSBO bindings used by neighbor search stages
+const (
+	BINDING_PARTICLES = iota
+	BINDING_INDEX
+	BINDING_CELL_COUNT
+	BINDING_CELL_START
+	BINDING_PARTICLE_CELL
+	BINDING_SORTED_PARTICLES
+)
+
+// Neighbor search stage, fills index with lists of neighbors of every particle.
+// Index holds `indexMaxNeighbors` slots per particle, list is terminated by INDEX_EMPTY_SLOT
+// unless it occupies all slots. Particles and index are bound to BINDING_PARTICLES
+// and BINDING_INDEX when Update is called.
+type NeighborSearch interface {
+	Update(countParticles uint32) error
+	Techniques() []*core.Technique // techniques of all stages
+	Delete()                       // deletes techniques and buffers
+}
+
+// Brute force neighbor search, compares every pair of particles
+type BruteForceNeighborSearch struct {
+	indexUpdate *core.Technique // a technique used to update index
+	indexClear  *core.Technique // a technique used to clear index
+}
+
+func NewBruteForceNeighborSearch(indexUpdate, indexClear *core.Technique) *BruteForceNeighborSearch {
+	return &BruteForceNeighborSearch{
+		indexUpdate: indexUpdate,
+		indexClear:  indexClear,
+	}
+}
+
+func (bf *BruteForceNeighborSearch) Update(countParticles uint32) error {
+	if bf.indexClear != nil {
+		if err := dispatch(bf.indexClear, countParticles, workgroups(countParticles), 1); err != nil {
+			return err
+		}
+	}
+	if bf.indexUpdate != nil {
+		return dispatch(bf.indexUpdate, countParticles, workgroups(countParticles), workgroups(countParticles))
+	}
+	return nil
+}
+
+func (bf *BruteForceNeighborSearch) Delete() {
+	for _, t := range bf.Techniques() {
+		deleteTechnique(t)
+	}
+}
+
+func (bf *BruteForceNeighborSearch) Techniques() []*core.Technique {
+	techniques := make([]*core.Technique, 0, 2)
+	for _, t := range []*core.Technique{bf.indexUpdate, bf.indexClear} {
+		if t != nil {
+			techniques = append(techniques, t)
+		}
+	}
+	return techniques
+}
+
+// Uniform grid neighbor search. Particles are hashed to cells of size `h`,
+// sorted by cells using counting sort and every particle scans 3x3 neighboring cells.
+type GridNeighborSearch struct {
+	gridClear       *core.Technique         // clears cells counters
+	gridCount       *core.Technique         // counts particles in cells
+	gridPrefixSum   *core.Technique         // calculates cells starts
+	gridSort        *core.Technique         // sorts particles by cells
+	gridNeighbors   *core.Technique         // fills index from neighboring cells
+	gridCells       uint32                  // number of cells in hash table
+	countParticles  uint32                  // number of particles buffers are allocated for
+	cellCountVbo    core.VertexBufferObject // number of particles in every cell
+	cellStartVbo    core.VertexBufferObject // offset of cell in sorted particles
+	particleCellVbo core.VertexBufferObject // cell and rank in cell of every particle
+	sortedVbo       core.VertexBufferObject // particles' ids sorted by cells
+}
+
+// creates grid neighbor search with `gridCells` cells in hash table,
+// number of cells is rounded up to multiple of WORKGROUP_SIZE
+func NewGridNeighborSearch(gridClear, gridCount, gridPrefixSum, gridSort, gridNeighbors *core.Technique, gridCells uint32) (*GridNeighborSearch, error) {
+	gridCells = workgroups(gridCells) * WORKGROUP_SIZE
+
+	gs := GridNeighborSearch{
+		gridClear:     gridClear,
+		gridCount:     gridCount,
+		gridPrefixSum: gridPrefixSum,
+		gridSort:      gridSort,
+		gridNeighbors: gridNeighbors,
+		gridCells:     gridCells,
+	}
+
+	for _, t := range []*core.Technique{gridCount, gridPrefixSum, gridNeighbors} {
+		if err := t.SetUniformUint("grid_cells", gridCells); err != nil {
+			return nil, err
+		}
+	}
+
+	gs.cellCountVbo = core.MakeVertexBufferObject(0, nil)
+	gs.cellStartVbo = core.MakeVertexBufferObject(0, nil)
+	gs.particleCellVbo = core.MakeVertexBufferObject(0, nil)
+	gs.sortedVbo = core.MakeVertexBufferObject(0, nil)
+
+	cellsSizeBytes := gridCells * uint32(unsafe.Sizeof(uint32(0)))
+	for _, vbo := range []core.VertexBufferObject{gs.cellCountVbo, gs.cellStartVbo} {
+		if err := vbo.SetData(nil, cellsSizeBytes); err != nil {
+			gs.deleteBuffers()
+			return nil, err
+		}
+	}
+
+	return &gs, nil
+}
+
+func (gs *GridNeighborSearch) Techniques() []*core.Technique {
+	return []*core.Technique{gs.gridClear, gs.gridCount, gs.gridPrefixSum, gs.gridSort, gs.gridNeighbors}
+}
+
+func (gs *GridNeighborSearch) Delete() {
+	for _, t := range gs.Techniques() {
+		deleteTechnique(t)
+	}
+	gs.deleteBuffers()
+}
+
+func (gs *GridNeighborSearch) deleteBuffers() {
+	for _, vbo := range []core.VertexBufferObject{gs.cellCountVbo, gs.cellStartVbo, gs.particleCellVbo, gs.sortedVbo} {
+		vbo.Delete()
+	}
+}
+
+func (gs *GridNeighborSearch) Update(countParticles uint32) error {
+	if countParticles != gs.countParticles {
+		if err := gs.particleCellVbo.SetData(nil, countParticles*2*uint32(unsafe.Sizeof(uint32(0)))); err != nil {
+			return err
+		}
+		if err := gs.sortedVbo.SetData(nil, countParticles*uint32(unsafe.Sizeof(uint32(0)))); err != nil {
+			return err
+		}
+		gs.countParticles = countParticles
+	}
+
+	unbindCellCount := gs.cellCountVbo.BindBase(gl.SHADER_STORAGE_BUFFER, BINDING_CELL_COUNT)
+	defer unbindCellCount()
+	unbindCellStart := gs.cellStartVbo.BindBase(gl.SHADER_STORAGE_BUFFER, BINDING_CELL_START)
+	defer unbindCellStart()
+	unbindParticleCell := gs.particleCellVbo.BindBase(gl.SHADER_STORAGE_BUFFER, BINDING_PARTICLE_CELL)
+	defer unbindParticleCell()
+	unbindSorted := gs.sortedVbo.BindBase(gl.SHADER_STORAGE_BUFFER, BINDING_SORTED_PARTICLES)
+	defer unbindSorted()
+
+	stages := []struct {
+		technique *core.Technique
+		groups    uint32
+	}{
+		{gs.gridClear, workgroups(gs.gridCells)},
+		{gs.gridCount, workgroups(countParticles)},
+		{gs.gridPrefixSum, 1},
+		{gs.gridSort, workgroups(countParticles)},
+		{gs.gridNeighbors, workgroups(countParticles)},
+	}
+	for _, stage := range stages {
+		if err := dispatch(stage.technique, countParticles, stage.groups, 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package particles
+
+import "fmt"
+import "math"
+import "reflect"
+import "github.com/dmarychev/gazebo/core"
+import "github.com/dmarychev/gazebo/inspect"
+
+// SSBO bindings of obstacles used by collide_obstacles stage
+const (
+	BINDING_OBSTACLES         = BINDING_SORTED_PARTICLES + 1 + iota
+	BINDING_OBSTACLE_VERTICES // vertices of polygons
+)
+
+// shape of obstacle, must match OBSTACLE_* in sph/collide_obstacles.cs
+type ObstacleType uint32
+
+const (
+	OBSTACLE_CIRCLE  ObstacleType = iota // disk of Radius around Center
+	OBSTACLE_SEGMENT                     // segment between two vertices of thickness 2*Radius
+	OBSTACLE_POLYGON                     // closed polygon of vertices
+)
+
+// Static obstacle particles collide with. Particles closer than BOUNDARY_EPS to
+// obstacle's surface are moved out of it, their velocity is split into normal
+// and tangential parts, normal part is reversed and multiplied by Restitution
+// and tangential one is reduced by Friction. Particles fast enough to cross thin
+// segment within one step pass through it.
+type Obstacle struct {
+	Type        ObstacleType // shape
+	Center      core.Vec2    // center of circle
+	Radius      float32      // radius of circle or half of thickness of segment
+	Vertices    []core.Vec2  // ends of segment or vertices of polygon
+	Restitution float32      // fraction of normal velocity kept, 0 stops particle, 1 is elastic collision
+	Friction    float32      // fraction of tangential velocity lost, 0 is frictionless
+}
+
+func NewCircleObstacle(center core.Vec2, radius float32) Obstacle {
+	return Obstacle{Type: OBSTACLE_CIRCLE, Center: center, Radius: radius}
+}
+
+func NewSegmentObstacle(a, b core.Vec2, thickness float32) Obstacle {
+	return Obstacle{Type: OBSTACLE_SEGMENT, Vertices: []core.Vec2{a, b}, Radius: thickness / 2}
+}
+
+func NewPolygonObstacle(vertices []core.Vec2) Obstacle {
+	return Obstacle{Type: OBSTACLE_POLYGON, Vertices: vertices}
+}
+
+func (o *Obstacle) Validate() error {
+	switch o.Type {
+	case OBSTACLE_CIRCLE:
+		if o.Radius <= 0 {
+			return fmt.Errorf("circle obstacle requires positive radius, got %v", o.Radius)
+		}
+	case OBSTACLE_SEGMENT:
+		if len(o.Vertices) != 2 {
+			return fmt.Errorf("segment obstacle requires 2 vertices, got %v", len(o.Vertices))
+		}
+	case OBSTACLE_POLYGON:
+		if len(o.Vertices) < 3 {
+			return fmt.Errorf("polygon obstacle requires at least 3 vertices, got %v", len(o.Vertices))
+		}
+	default:
+		return fmt.Errorf("unknown obstacle type %v", o.Type)
+	}
+	return nil
+}
+
+// obstacle in SSBO "Obstacles", must match std430 layout of Obstacle in sph/collide_obstacles.cs
+type gpuObstacle struct {
+	A             core.Vec2 // center of circle or the first end of segment
+	B             core.Vec2 // the second end of segment
+	Radius        float32
+	Restitution   float32
+	Friction      float32
+	Type          uint32
+	FirstVertex   uint32 // index of the first vertex of polygon in SSBO "ObstacleVertices"
+	CountVertices uint32 // number of vertices of polygon
+}
+
+// converts obstacles to contents of SSBOs "Obstacles" and "ObstacleVertices"
+func packObstacles(obstacles []Obstacle) ([]gpuObstacle, []core.Vec2) {
+	packed := make([]gpuObstacle, len(obstacles))
+	vertices := make([]core.Vec2, 0)
+	for i, o := range obstacles {
+		packed[i] = gpuObstacle{
+			A:           o.Center,
+			Radius:      o.Radius,
+			Restitution: o.Restitution,
+			Friction:    o.Friction,
+			Type:        uint32(o.Type),
+		}
+		switch o.Type {
+		case OBSTACLE_SEGMENT:
+			packed[i].A, packed[i].B = o.Vertices[0], o.Vertices[1]
+		case OBSTACLE_POLYGON:
+			packed[i].FirstVertex, packed[i].CountVertices = uint32(len(vertices)), uint32(len(o.Vertices))
+			vertices = append(vertices, o.Vertices...)
+		}
+	}
+	return packed, vertices
+}
+
+// checks that gpuObstacle matches std430 layout of obstacles in SSBO "Obstacles"
+// declared by technique, techniques without it are accepted as is
+func ValidateObstacleLayout(t *core.Technique) error {
+	return inspect.ValidateBufferLayout(t, "Obstacles", "obstacles", reflect.TypeOf(gpuObstacle{}))
+}
+
+// closest point of segment ab to r
+func closestOnSegment(r, a, b core.Vec2) core.Vec2 {
+	ab := b.Sub(a)
+	t := float32(0)
+	if l2 := ab.Dot(ab); l2 > 0 {
+		t = float32(math.Max(0, math.Min(1, float64(r.Sub(a).Dot(ab)/l2))))
+	}
+	return core.Vec2{X: a.X + t*ab.X, Y: a.Y + t*ab.Y}
+}
+
+// surface point of obstacle closest to r and outward normal at it, `inside` is true
+// if r is inside of obstacle, like closest_surface in sph/collide_obstacles.cs
+func (o *Obstacle) closestSurface(r core.Vec2) (q, n core.Vec2, inside bool) {
+	normal := func(d core.Vec2, fallback core.Vec2) core.Vec2 {
+		if l := d.Length(); l > 0 {
+			return core.Vec2{X: d.X / l, Y: d.Y / l}
+		}
+		return fallback
+	}
+
+	switch o.Type {
+	case OBSTACLE_CIRCLE:
+		n = normal(r.Sub(o.Center), core.Vec2{X: 0, Y: 1})
+		return core.Vec2{X: o.Center.X + n.X*o.Radius, Y: o.Center.Y + n.Y*o.Radius}, n, r.Sub(o.Center).Length() < o.Radius
+	case OBSTACLE_SEGMENT:
+		a, b := o.Vertices[0], o.Vertices[1]
+		c := closestOnSegment(r, a, b)
+		n = normal(r.Sub(c), normal(core.Vec2{X: a.Y - b.Y, Y: b.X - a.X}, core.Vec2{X: 0, Y: 1}))
+		return core.Vec2{X: c.X + n.X*o.Radius, Y: c.Y + n.Y*o.Radius}, n, r.Sub(c).Length() < o.Radius
+	}
+
+	// polygon: the closest point of edges, inside by even-odd rule
+	minDist := float32(math.Inf(1))
+	count := len(o.Vertices)
+	for i := 0; i < count; i++ {
+		a, b := o.Vertices[i], o.Vertices[(i+1)%count]
+		if c := closestOnSegment(r, a, b); r.Sub(c).Length() < minDist {
+			minDist, q = r.Sub(c).Length(), c
+		}
+		if (a.Y > r.Y) != (b.Y > r.Y) && r.X < a.X+(r.Y-a.Y)*(b.X-a.X)/(b.Y-a.Y) {
+			inside = !inside
+		}
+	}
+	if inside {
+		return q, normal(q.Sub(r), core.Vec2{X: 0, Y: 1}), true
+	}
+	return q, normal(r.Sub(q), core.Vec2{X: 0, Y: 1}), false
+}
+
+// moves particle out of obstacle and changes its velocity, like collide in sph/collide_obstacles.cs
+func (o *Obstacle) collide(p *Particle) {
+	q, n, inside := o.closestSurface(p.R)
+	if !inside && p.R.Sub(q).Length() >= BOUNDARY_EPS {
+		return
+	}
+
+	p.R = core.Vec2{X: q.X + n.X*BOUNDARY_EPS, Y: q.Y + n.Y*BOUNDARY_EPS}
+
+	vn := p.V.Dot(n)
+	if vn >= 0 {
+		return
+	}
+	vt := core.Vec2{X: p.V.X - vn*n.X, Y: p.V.Y - vn*n.Y}
+	p.V = core.Vec2{
+		X: vt.X*(1-o.Friction) - o.Restitution*vn*n.X,
+		Y: vt.Y*(1-o.Friction) - o.Restitution*vn*n.Y,
+	}
+}
@@ -0,0 +1,130 @@
+package particles
+
+import "errors"
+import "fmt"
+import "io/ioutil"
+import "os"
+import "path/filepath"
+import "regexp"
+import "sort"
+import "strconv"
+import "strings"
+import "github.com/dmarychev/gazebo/core"
+
+// Directories searched for `#include "file"` after directory of including file
+var ShaderIncludePath []string
+
+var includeDirective = regexp.MustCompile(`^\s*#\s*include\s+"([^"]+)"\s*$`)
+
+// position of preprocessed line in original file
+type lineOrigin struct {
+	file string
+	line int
+}
+
+// Resolves #include directives of shader file. Every file is included at most once,
+// so shared declarations need no include guards. Drivers disagree on reporting
+// source string numbers set by #line, so instead of emitting it preprocessor
+// remembers origin of every line to map compiler's log back to files.
+type shaderPreprocessor struct {
+	origins  []lineOrigin    // origins of preprocessed lines
+	included map[string]bool // absolute paths of files already included
+	text     strings.Builder // preprocessed text
+}
+
+func preprocessShaderFile(fileName string) (*shaderPreprocessor, error) {
+	pp := shaderPreprocessor{included: make(map[string]bool)}
+	if err := pp.include(fileName); err != nil {
+		return nil, err
+	}
+	return &pp, nil
+}
+
+func (pp *shaderPreprocessor) resolve(includeName, dir string) (string, error) {
+	if filepath.IsAbs(includeName) {
+		return includeName, nil
+	}
+	for _, searchDir := range append([]string{dir}, ShaderIncludePath...) {
+		path := filepath.Join(searchDir, includeName)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("include file %q not found", includeName)
+}
+
+func (pp *shaderPreprocessor) include(fileName string) error {
+	absName, err := filepath.Abs(fileName)
+	if err != nil {
+		return err
+	}
+	if pp.included[absName] {
+		return nil
+	}
+	pp.included[absName] = true
+
+	text, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return err
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(text), "\n"), "\n")
+	for i, line := range lines {
+		match := includeDirective.FindStringSubmatch(line)
+		if match == nil {
+			pp.text.WriteString(line)
+			pp.text.WriteByte('\n')
+			pp.origins = append(pp.origins, lineOrigin{file: fileName, line: i + 1})
+			continue
+		}
+
+		includeName, err := pp.resolve(match[1], filepath.Dir(fileName))
+		if err != nil {
+			return fmt.Errorf("%v:%v: %v", fileName, i+1, err)
+		}
+		if err := pp.include(includeName); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// matches "0:12(5): error" (Mesa), "0(12) : error" (NVIDIA) and "ERROR: 0:12:" (AMD)
+var compileLogLocation = regexp.MustCompile(`(?m)^(\s*(?:ERROR: |WARNING: )?)(\d+)([:(])(\d+)`)
+
+// replaces locations in preprocessed text with locations in files in compiler's log
+func (pp *shaderPreprocessor) mapLog(log string) string {
+	return compileLogLocation.ReplaceAllStringFunc(log, func(location string) string {
+		match := compileLogLocation.FindStringSubmatch(location)
+		line, _ := strconv.Atoi(match[4])
+		if match[2] != "0" || line < 1 || line > len(pp.origins) {
+			return location
+		}
+		origin := pp.origins[line-1]
+		return fmt.Sprintf("%v%v%v%v", match[1], origin.file, match[3], origin.line)
+	})
+}
+
+// absolute paths of shader file and all files included by it
+func (pp *shaderPreprocessor) files() []string {
+	files := make([]string, 0, len(pp.included))
+	for file := range pp.included {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files
+}
+
+func (pp *shaderPreprocessor) Text() string {
+	return pp.text.String()
+}
+
+// rewrites locations in shader compilation error, if `err` is one for preprocessed text
+func (pp *shaderPreprocessor) mapError(err error) error {
+	var compileErr *core.CompileError
+	if errors.As(err, &compileErr) && fmt.Sprint(compileErr.Source) == pp.Text() {
+		compileErr.Log = pp.mapLog(compileErr.Log)
+	}
+	return err
+}
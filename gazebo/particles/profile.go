@@ -0,0 +1,153 @@
+package particles
+
+import "fmt"
+import "path/filepath"
+import "strings"
+import "time"
+import "github.com/dmarychev/gazebo/core"
+
+const (
+	PROFILE_WINDOW = 60 // number of the last samples averaged per stage
+)
+
+// GPU time of stage averaged over the last PROFILE_WINDOW measurements
+type StageTime struct {
+	Name    string        // stage name
+	Mean    time.Duration // mean time of samples in window
+	Max     time.Duration // maximal time of samples in window
+	Samples int           // number of samples in window
+}
+
+func (st StageTime) String() string {
+	return fmt.Sprintf("%v %.3fms", st.Name, float64(st.Mean)/float64(time.Millisecond))
+}
+
+// formats stage times with their total, e.g. to log them
+func FormatStageTimes(times []StageTime) string {
+	var total time.Duration
+	parts := make([]string, len(times))
+	for i, st := range times {
+		parts[i] = st.String()
+		total += st.Mean
+	}
+	return fmt.Sprintf("%v, total %.3fms", strings.Join(parts, ", "), float64(total)/float64(time.Millisecond))
+}
+
+// timer of single stage
+type stageTimer struct {
+	name    string                        // stage name
+	pending []core.QueryObject            // queries waiting for results, in order of issue
+	samples [PROFILE_WINDOW]time.Duration // ring of the last samples
+	count   int                           // number of samples measured
+}
+
+func (st *stageTimer) time() StageTime {
+	n := st.count
+	if n > PROFILE_WINDOW {
+		n = PROFILE_WINDOW
+	}
+	result := StageTime{Name: st.name, Samples: n}
+	for _, sample := range st.samples[:n] {
+		result.Mean += sample
+		if sample > result.Max {
+			result.Max = sample
+		}
+	}
+	if n > 0 {
+		result.Mean /= time.Duration(n)
+	}
+	return result
+}
+
+// Measures GPU time of stages with time elapsed queries. Results are collected
+// when they become available, so measuring doesn't wait for GPU and the last
+// measurements of stages appear in StageTimes a few frames later.
+type Profiler struct {
+	stages []*stageTimer      // stages in order of the first measurement
+	free   []core.QueryObject // queries whose results are collected
+}
+
+func NewProfiler() *Profiler {
+	return &Profiler{}
+}
+
+func (p *Profiler) stage(name string) *stageTimer {
+	for _, st := range p.stages {
+		if st.name == name {
+			return st
+		}
+	}
+	st := &stageTimer{name: name}
+	p.stages = append(p.stages, st)
+	return st
+}
+
+func (p *Profiler) query() core.QueryObject {
+	if n := len(p.free); n > 0 {
+		q := p.free[n-1]
+		p.free = p.free[:n-1]
+		return q
+	}
+	return core.MakeQueryObject()
+}
+
+// measures GPU time of commands issued by `f` as stage `name`
+func (p *Profiler) Measure(name string, f func() error) error {
+	st := p.stage(name)
+	q := p.query()
+	end := q.BeginTimeElapsed()
+	err := f()
+	end()
+	st.pending = append(st.pending, q)
+	if err != nil {
+		return err
+	}
+	return p.collect(st)
+}
+
+// moves available results of stage's queries to its samples
+func (p *Profiler) collect(st *stageTimer) error {
+	for len(st.pending) > 0 && st.pending[0].Available() {
+		q := st.pending[0]
+		elapsed, err := q.Elapsed()
+		if err != nil {
+			return err
+		}
+		st.samples[st.count%PROFILE_WINDOW] = elapsed
+		st.count++
+		st.pending = st.pending[1:]
+		p.free = append(p.free, q)
+	}
+	return nil
+}
+
+// deletes queries, including ones waiting for results
+func (p *Profiler) Delete() {
+	for _, st := range p.stages {
+		for _, q := range st.pending {
+			q.Delete()
+		}
+		st.pending = nil
+	}
+	for _, q := range p.free {
+		q.Delete()
+	}
+	p.free = nil
+}
+
+// times of measured stages in order they were measured first
+func (p *Profiler) StageTimes() []StageTime {
+	times := make([]StageTime, len(p.stages))
+	for i, st := range p.stages {
+		times[i] = st.time()
+	}
+	return times
+}
+
+// stage name of technique loaded from file is the file name without extension
+func techniqueName(t *core.Technique) string {
+	if sf, ok := loadedTechniques[t]; ok {
+		return strings.TrimSuffix(filepath.Base(sf.name), filepath.Ext(sf.name))
+	}
+	return fmt.Sprintf("technique %p", t)
+}
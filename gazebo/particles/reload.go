@@ -0,0 +1,109 @@
+package particles
+
+import "fmt"
+import "log"
+import "os"
+import "time"
+import "github.com/dmarychev/gazebo/core"
+
+// Shader files technique was loaded from, used to recompile it when they change
+type shaderFiles struct {
+	name     string                                    // shader files as passed to loader, for logging
+	load     func() (*core.Technique, []string, error) // compiles technique and lists all its files
+	modTimes map[string]time.Time                      // modification times of files at the last load
+}
+
+// techniques created by New*TechniqueFromFile
+var loadedTechniques = make(map[*core.Technique]*shaderFiles)
+
+func loadTechnique(name string, load func() (*core.Technique, []string, error)) (*core.Technique, error) {
+	technique, files, err := load()
+	if err != nil {
+		return nil, err
+	}
+	loadedTechniques[technique] = &shaderFiles{name: name, load: load, modTimes: modTimes(files)}
+	return technique, nil
+}
+
+// modification times of files, zero for files which can't be accessed
+func modTimes(files []string) map[string]time.Time {
+	times := make(map[string]time.Time, len(files))
+	for _, file := range files {
+		if info, err := os.Stat(file); err == nil {
+			times[file] = info.ModTime()
+		} else {
+			times[file] = time.Time{}
+		}
+	}
+	return times
+}
+
+// modification times of files if any was modified since the last load, nil otherwise
+func (sf *shaderFiles) changed() map[string]time.Time {
+	current := modTimes(keys(sf.modTimes))
+	for file, modTime := range current {
+		if !modTime.IsZero() && !modTime.Equal(sf.modTimes[file]) {
+			return current
+		}
+	}
+	return nil
+}
+
+func keys(m map[string]time.Time) []string {
+	files := make([]string, 0, len(m))
+	for file := range m {
+		files = append(files, file)
+	}
+	return files
+}
+
+// deletes technique and forgets its files
+func deleteTechnique(t *core.Technique) {
+	delete(loadedTechniques, t)
+	t.Delete()
+}
+
+// deletes techniques skipping nil ones, e.g. on failure to create stage of several techniques
+func deleteTechniques(techniques []*core.Technique) {
+	for _, t := range techniques {
+		if t != nil {
+			deleteTechnique(t)
+		}
+	}
+}
+
+// Recompiles technique loaded from files if they changed and replaces its program.
+// If the new program fails to compile or link, the error is logged and technique
+// keeps running the old one until files change again. If replacing fails, which
+// happens before the swap only, technique keeps the old program too, and replacing
+// is retried on the next call.
+func reloadTechnique(t *core.Technique) error {
+	sf, ok := loadedTechniques[t]
+	if !ok {
+		return nil
+	}
+	current := sf.changed()
+	if current == nil {
+		return nil
+	}
+
+	// an error pending from other calls would be taken for failure to compile
+	if err := core.GetError(); err != nil {
+		return err
+	}
+
+	log.Printf("Reload technique: %v\n", sf.name)
+	nt, files, err := sf.load()
+	if err != nil {
+		log.Printf("Failed to reload %v, keep previous program:\n%v\n", sf.name, err)
+		sf.modTimes = current
+		return nil
+	}
+
+	if err := t.Replace(nt); err != nil {
+		nt.Delete()
+		return fmt.Errorf("failed to replace program of %v: %w", sf.name, err)
+	}
+	sf.modTimes = modTimes(files)
+	return nil
+}
@@ -1,5 +1,8 @@
 package particles
 
+import "errors"
+import "fmt"
+import "time"
 import "github.com/go-gl/gl/v4.6-core/gl"
 import "github.com/dmarychev/gazebo/core"
 import "unsafe"
@@ -10,40 +13,106 @@ const (
 	WORKGROUP_SIZE = 16 // must match local_size_<> in compute shaders
 )
 
+// indices of vertex attributes, must match locations in vertex shaders
 const (
 	ATTRIB_COORDINATES = iota // index of coordinates attribute buffer
+	ATTRIB_VELOCITY           // index of velocity attribute buffer
+	ATTRIB_FORCE              // index of total force attribute buffer
+	ATTRIB_PRESSURE           // index of pressure attribute buffer
+	ATTRIB_DENSITY            // index of density attribute buffer
+	ATTRIB_MASS               // index of mass attribute buffer
 )
 
+// binds fields of 2D particles in currently bound array buffer to vertex attributes
 func AttachVertexAttributes() func() {
-	gl.EnableVertexAttribArray(ATTRIB_COORDINATES)
-	gl.VertexAttribPointer(ATTRIB_COORDINATES, 2, gl.FLOAT, false, int32(unsafe.Sizeof(Particle{})), unsafe.Pointer(unsafe.Offsetof(Particle{}.R)))
-	return func() {
-		gl.DisableVertexAttribArray(ATTRIB_COORDINATES)
+	return layout2D.attachVertexAttributes()
+}
+
+// number of workgroups covering `count` invocations
+func workgroups(count uint32) uint32 {
+	return (count + WORKGROUP_SIZE - 1) / WORKGROUP_SIZE
+}
+
+// runs compute technique over given number of workgroups and waits for its results in SSBOs,
+// number of particles is passed in uniform count_particles if technique declares it
+func dispatch(t *core.Technique, countParticles, groupsX, groupsY uint32) error {
+	if err := t.SetUniformUint("count_particles", countParticles); err != nil && !errors.Is(err, core.ErrUnknownUniform) {
+		return err
+	}
+
+	disable, err := t.Enable()
+	if err != nil {
+		return err
+	}
+	defer disable()
+
+	gl.DispatchCompute(groupsX, groupsY, 1)
+	if err := core.GetError(); err != nil {
+		return err
 	}
+
+	gl.MemoryBarrier(gl.SHADER_STORAGE_BARRIER_BIT)
+	return core.GetError()
 }
 
+// way particles are rendered
+type RenderMode int
+
+const (
+	RENDER_POINTS RenderMode = iota // particles as points by render technique
+	RENDER_FLUID                    // fluid surface by FluidRenderer
+)
+
 type RenderState struct {
 	updateTechniques  []*core.Technique       // a techniques used to update system
 	renderTechnique   *core.Technique         // a technique used to render system
-	indexUpdate       *core.Technique         // a technique used to update index system
-	indexClear        *core.Technique         // a technique used to clear index system
+	neighborSearch    NeighborSearch          // a stage used to update index system
 	indexMaxNeighbors uint32                  // maximum neighbors in index
 	vao               core.VertexArrayObject  // array buffer associated with the state
 	vbo               core.VertexBufferObject // a VBO containing particles' state.
 	indexVbo          core.VertexBufferObject // a VBO containing index data
 	countParticles    uint32                  // number of particles in process
+	fluidRenderer     *FluidRenderer          // renders fluid surface in RENDER_FLUID mode
+	renderMode        RenderMode              // current way of rendering
+	coloring          Coloring                // coloring of points by scalar field
+	colorParticles    []Particle              // particles read back to calculate auto range of coloring
+	colorParticles3   []Particle3             // 3D particles read back to calculate auto range of coloring
+	colorRange        core.Vec2               // auto range of coloring calculated at colorRangeTime
+	colorRangeTime    time.Time               // time of the last auto range calculation, zero to recalculate it
+	profiler          *Profiler               // measures GPU time of stages, nil if profiling is off
+	obstacleVbo       core.VertexBufferObject // a VBO containing obstacles
+	obstacleVertexVbo core.VertexBufferObject // a VBO containing vertices of polygon obstacles
+	countObstacles    uint32                  // number of obstacles
+	layout            *particleLayout         // layout of 2D or 3D particles in vbo
+	camera            Camera                  // view of 3D particles
+	orthoCamera       OrthoCamera             // view of 2D particles
+}
+
+// creates state of 2D particles
+func NewRenderState(render *core.Technique, neighborSearch NeighborSearch, indexMaxNeighbors uint32) *RenderState {
+	return newRenderState(&layout2D, render, neighborSearch, indexMaxNeighbors)
 }
 
-func NewRenderState(render, indexUpdate, indexClear *core.Technique, indexMaxNeighbors uint32) *RenderState {
+// creates state of 3D particles rendered in perspective of camera
+func NewRenderState3(render *core.Technique, neighborSearch NeighborSearch, indexMaxNeighbors uint32) *RenderState {
+	return newRenderState(&layout3D, render, neighborSearch, indexMaxNeighbors)
+}
+
+func newRenderState(layout *particleLayout, render *core.Technique, neighborSearch NeighborSearch, indexMaxNeighbors uint32) *RenderState {
 	rs := RenderState{
 		updateTechniques:  make([]*core.Technique, 0, 10),
 		renderTechnique:   render,
-		indexUpdate:       indexUpdate,
-		indexClear:        indexClear,
+		neighborSearch:    neighborSearch,
 		indexMaxNeighbors: indexMaxNeighbors,
+		layout:            layout,
+		camera:            DefaultCamera(),
+		orthoCamera:       DefaultOrthoCamera(),
 	}
+	rs.vao = core.MakeVertexArrayObject()
 	rs.vbo = core.MakeVertexBufferObject(0, nil)
 	rs.indexVbo = core.MakeVertexBufferObject(0, nil)
+	rs.obstacleVbo = core.MakeVertexBufferObject(0, nil)
+	rs.obstacleVertexVbo = core.MakeVertexBufferObject(0, nil)
 	return &rs
 }
 
@@ -51,16 +120,118 @@ func (rs *RenderState) AddUpdateTechnique(t *core.Technique) {
 	rs.updateTechniques = append(rs.updateTechniques, t)
 }
 
-func (rs *RenderState) SetParticles(particles []Particle) {
+// all techniques used by the state
+func (rs *RenderState) techniques() []*core.Technique {
+	techniques := append([]*core.Technique{rs.renderTechnique}, rs.updateTechniques...)
+	if rs.neighborSearch != nil {
+		techniques = append(techniques, rs.neighborSearch.Techniques()...)
+	}
+	if rs.fluidRenderer != nil {
+		techniques = append(techniques, rs.fluidRenderer.Techniques()...)
+	}
+	return techniques
+}
+
+// number of dimensions of particles, 2 or 3
+func (rs *RenderState) Dimensions() int {
+	return rs.layout.dimensions
+}
+
+func (rs *RenderState) checkDimensions(dimensions int) error {
+	if dimensions != rs.layout.dimensions {
+		return fmt.Errorf("particles are %vD, state is %vD", dimensions, rs.layout.dimensions)
+	}
+	return nil
+}
+
+func (rs *RenderState) SetParticles(particles []Particle) error {
+	if err := rs.checkDimensions(2); err != nil {
+		return err
+	}
 	if len(particles) > 0 {
-		sizeBytes := uint32(len(particles)) * uint32(unsafe.Sizeof(Particle{}))
-		rs.vbo.SetData(gl.Ptr(particles), sizeBytes)
-		rs.countParticles = uint32(len(particles))
-		rs.indexVbo.SetData(nil, rs.countParticles*rs.indexMaxNeighbors*uint32(unsafe.Sizeof(uint32(0))))
+		return rs.setParticleData(gl.Ptr(particles), len(particles))
 	}
+	return nil
 }
 
-func (rs *RenderState) Update() {
+func (rs *RenderState) SetParticles3(particles []Particle3) error {
+	if err := rs.checkDimensions(3); err != nil {
+		return err
+	}
+	if len(particles) > 0 {
+		return rs.setParticleData(gl.Ptr(particles), len(particles))
+	}
+	return nil
+}
+
+// uploads `count` particles of state's layout and reallocates index for them
+func (rs *RenderState) setParticleData(data unsafe.Pointer, count int) error {
+	if err := rs.vbo.SetData(data, uint32(count)*rs.layout.size()); err != nil {
+		return err
+	}
+	rs.countParticles = uint32(count)
+	return rs.indexVbo.SetData(nil, rs.countParticles*rs.indexMaxNeighbors*uint32(unsafe.Sizeof(uint32(0))))
+}
+
+// uploads obstacles and passes their number in uniform count_obstacles to update techniques declaring it
+func (rs *RenderState) SetObstacles(obstacles []Obstacle) error {
+	if len(obstacles) > 0 && rs.layout != &layout2D {
+		return fmt.Errorf("obstacles require 2D particles, state is %vD", rs.layout.dimensions)
+	}
+	packed, vertices := packObstacles(obstacles)
+	if len(packed) > 0 {
+		if err := rs.obstacleVbo.SetData(gl.Ptr(packed), uint32(len(packed))*uint32(unsafe.Sizeof(gpuObstacle{}))); err != nil {
+			return err
+		}
+	}
+	if len(vertices) > 0 {
+		if err := rs.obstacleVertexVbo.SetData(gl.Ptr(vertices), uint32(len(vertices))*uint32(unsafe.Sizeof(core.Vec2{}))); err != nil {
+			return err
+		}
+	}
+	rs.countObstacles = uint32(len(packed))
+
+	for _, t := range rs.updateTechniques {
+		if err := t.SetUniformUint("count_obstacles", rs.countObstacles); err != nil && !errors.Is(err, core.ErrUnknownUniform) {
+			return err
+		}
+	}
+	return nil
+}
+
+// reads current particles' state from GPU memory into `particles`
+func (rs *RenderState) GetParticles(particles []Particle) error {
+	if err := rs.checkDimensions(2); err != nil {
+		return err
+	}
+	if len(particles) > 0 {
+		return rs.getParticleData(gl.Ptr(particles), len(particles))
+	}
+	return nil
+}
+
+// reads current state of 3D particles from GPU memory into `particles`
+func (rs *RenderState) GetParticles3(particles []Particle3) error {
+	if err := rs.checkDimensions(3); err != nil {
+		return err
+	}
+	if len(particles) > 0 {
+		return rs.getParticleData(gl.Ptr(particles), len(particles))
+	}
+	return nil
+}
+
+func (rs *RenderState) getParticleData(data unsafe.Pointer, count int) error {
+	// make sure writes from compute shaders are visible to readback
+	gl.MemoryBarrier(gl.BUFFER_UPDATE_BARRIER_BIT)
+	return rs.vbo.GetData(data, uint32(count)*rs.layout.size())
+}
+
+func (rs *RenderState) CountParticles() uint32 {
+	return rs.countParticles
+}
+
+func (rs *RenderState) Update() error {
 	/*
 		p_data := make([]float32, rs.countParticles*uint32(unsafe.Sizeof(Particle{}))/uint32(unsafe.Sizeof(float32(0))))
 		rs.vbo.GetData(gl.Ptr(p_data), rs.countParticles*uint32(unsafe.Sizeof(Particle{})))
@@ -68,65 +239,201 @@ func (rs *RenderState) Update() {
 		log.Printf("%v", p_data)
 		log.Printf("End of particles")
 	*/
-	unbindParticles := rs.vbo.BindBase(gl.SHADER_STORAGE_BUFFER, 0)
-	unbindIndex := rs.indexVbo.BindBase(gl.SHADER_STORAGE_BUFFER, 1)
-
-	if rs.indexClear != nil {
-		disable := rs.indexClear.Enable()
-
-		gl.DispatchCompute(rs.countParticles/WORKGROUP_SIZE, 1, 1)
-		core.CheckError()
+	unbindParticles := rs.vbo.BindBase(gl.SHADER_STORAGE_BUFFER, BINDING_PARTICLES)
+	defer unbindParticles()
+	unbindIndex := rs.indexVbo.BindBase(gl.SHADER_STORAGE_BUFFER, BINDING_INDEX)
+	defer unbindIndex()
+	if rs.countObstacles > 0 {
+		unbindObstacles := rs.obstacleVbo.BindBase(gl.SHADER_STORAGE_BUFFER, BINDING_OBSTACLES)
+		defer unbindObstacles()
+		unbindVertices := rs.obstacleVertexVbo.BindBase(gl.SHADER_STORAGE_BUFFER, BINDING_OBSTACLE_VERTICES)
+		defer unbindVertices()
+	}
 
-		gl.MemoryBarrier(gl.SHADER_STORAGE_BARRIER_BIT)
-		disable()
+	if rs.neighborSearch != nil {
+		err := rs.measure("neighbor_search", func() error {
+			return rs.neighborSearch.Update(rs.countParticles)
+		})
+		if err != nil {
+			return err
+		}
 		/*
 			data := make([]uint32, rs.countParticles*rs.indexMaxNeighbors, rs.countParticles*rs.indexMaxNeighbors)
 			rs.indexVbo.GetData(gl.Ptr(data), uint32(len(data))*uint32(unsafe.Sizeof(uint32(0))))
-			log.Printf("Cleared Index: ")
+			log.Printf("Updated Index: ")
 			log.Printf("%v", data)
 			log.Printf("End of index")*/
 	}
 
-	if rs.indexUpdate != nil {
-		disableUpdate := rs.indexUpdate.Enable()
-
-		gl.DispatchCompute(rs.countParticles/WORKGROUP_SIZE, rs.countParticles/WORKGROUP_SIZE, 1)
-		core.CheckError()
-
-		gl.MemoryBarrier(gl.SHADER_STORAGE_BARRIER_BIT)
-		disableUpdate()
-
-	}
-
 	for _, technique := range rs.updateTechniques {
-		disable := technique.Enable()
-
-		gl.DispatchCompute(rs.countParticles/WORKGROUP_SIZE, 1, 1)
-		core.CheckError()
-
-		gl.MemoryBarrier(gl.SHADER_STORAGE_BARRIER_BIT)
-		core.CheckError()
+		err := rs.measure(techniqueName(technique), func() error {
+			return dispatch(technique, rs.countParticles, workgroups(rs.countParticles), 1)
+		})
+		if err != nil {
+			return err
+		}
 		/*
 			p_data := make([]float32, rs.countParticles*uint32(unsafe.Sizeof(Particle{}))/uint32(unsafe.Sizeof(float32(0))))
 			rs.vbo.GetData(gl.Ptr(p_data), rs.countParticles*uint32(unsafe.Sizeof(Particle{})))
 			log.Printf("Updated Particles #: ")
 			log.Printf("%v", p_data)
 			log.Printf("End of particles #")*/
-
-		disable()
 	}
 
-	unbindIndex()
-	unbindParticles()
-
 	/*i_data := make([]uint32, rs.countParticles*rs.indexMaxNeighbors, rs.countParticles*rs.indexMaxNeighbors)
 	rs.indexVbo.GetData(gl.Ptr(i_data), uint32(len(i_data))*uint32(unsafe.Sizeof(uint32(0))))
 	log.Printf("Updated Index: ")
 	log.Printf("%v", i_data)
 	log.Printf("End of index")*/
+	return nil
+}
+
+// measures GPU time of `f` as stage `name` if profiling is on
+func (rs *RenderState) measure(name string, f func() error) error {
+	if rs.profiler == nil {
+		return f()
+	}
+	return rs.profiler.Measure(name, f)
+}
+
+// turns on measuring GPU time of update stages and rendering, times are reset when it's turned off
+func (rs *RenderState) SetProfiling(enabled bool) {
+	if !enabled {
+		if rs.profiler != nil {
+			rs.profiler.Delete()
+			rs.profiler = nil
+		}
+	} else if rs.profiler == nil {
+		rs.profiler = NewProfiler()
+	}
+}
+
+// GPU times of stages measured since profiling is on, nil if it's off
+func (rs *RenderState) StageTimes() []StageTime {
+	if rs.profiler == nil {
+		return nil
+	}
+	return rs.profiler.StageTimes()
+}
+
+// deletes techniques, buffers and everything else owned by the state
+func (rs *RenderState) Delete() {
+	deleteTechnique(rs.renderTechnique)
+	for _, t := range rs.updateTechniques {
+		deleteTechnique(t)
+	}
+	rs.updateTechniques = nil
+	if rs.neighborSearch != nil {
+		rs.neighborSearch.Delete()
+		rs.neighborSearch = nil
+	}
+	if rs.fluidRenderer != nil {
+		rs.fluidRenderer.Delete()
+		rs.fluidRenderer = nil
+	}
+	rs.SetProfiling(false)
+	for _, vbo := range []core.VertexBufferObject{rs.vbo, rs.indexVbo, rs.obstacleVbo, rs.obstacleVertexVbo} {
+		vbo.Delete()
+	}
+	rs.vao.Delete()
+	rs.countParticles, rs.countObstacles = 0, 0
+}
+
+// sets renderer of RENDER_FLUID mode, the state owns it and deletes previous one
+func (rs *RenderState) SetFluidRenderer(fr *FluidRenderer) {
+	if rs.fluidRenderer != nil && rs.fluidRenderer != fr {
+		rs.fluidRenderer.Delete()
+	}
+	rs.fluidRenderer = fr
+}
+
+func (rs *RenderState) SetRenderMode(mode RenderMode) error {
+	if mode == RENDER_FLUID && rs.fluidRenderer == nil {
+		return fmt.Errorf("no fluid renderer to render in fluid mode")
+	}
+	if mode == RENDER_FLUID && rs.layout != &layout2D {
+		return fmt.Errorf("fluid mode renders only 2D particles, state is %vD", rs.layout.dimensions)
+	}
+	rs.renderMode = mode
+	return nil
 }
 
-func (rs *RenderState) Render() {
+func (rs *RenderState) RenderMode() RenderMode {
+	return rs.renderMode
+}
+
+// colors points rendered in RENDER_POINTS mode by scalar field
+func (rs *RenderState) SetColoring(c Coloring) {
+	rs.coloring = c
+	rs.colorRangeTime = time.Time{}
+}
+
+func (rs *RenderState) Coloring() Coloring {
+	return rs.coloring
+}
+
+// sets view of 3D particles
+func (rs *RenderState) SetCamera(c Camera) {
+	rs.camera = c
+}
+
+func (rs *RenderState) Camera() Camera {
+	return rs.camera
+}
+
+// sets view of 2D particles
+func (rs *RenderState) SetOrthoCamera(c OrthoCamera) {
+	rs.orthoCamera = c
+}
+
+func (rs *RenderState) OrthoCamera() OrthoCamera {
+	return rs.orthoCamera
+}
+
+// sets coloring uniforms to render technique, automatic range is recalculated from
+// particles read back only every AUTO_RANGE_INTERVAL to keep rendering from stalling
+func (rs *RenderState) applyColoring() error {
+	valueRange := rs.coloring.Range
+	if rs.coloring.AutoRange && rs.coloring.Field != COLOR_FIELD_NONE {
+		if time.Since(rs.colorRangeTime) >= AUTO_RANGE_INTERVAL {
+			if err := rs.updateColorRange(); err != nil {
+				return err
+			}
+			rs.colorRangeTime = time.Now()
+		}
+		valueRange = rs.colorRange
+	}
+	return rs.coloring.apply(rs.renderTechnique, valueRange)
+}
+
+// calculates auto range of coloring's field from particles read back from GPU
+func (rs *RenderState) updateColorRange() error {
+	if rs.layout == &layout3D {
+		if len(rs.colorParticles3) != int(rs.countParticles) {
+			rs.colorParticles3 = make([]Particle3, rs.countParticles)
+		}
+		if err := rs.GetParticles3(rs.colorParticles3); err != nil {
+			return err
+		}
+		rs.colorRange = rs.coloring.autoRange(len(rs.colorParticles3), func(i int) float32 {
+			return rs.coloring.Field.value3(rs.colorParticles3[i])
+		})
+		return nil
+	}
+	if len(rs.colorParticles) != int(rs.countParticles) {
+		rs.colorParticles = make([]Particle, rs.countParticles)
+	}
+	if err := rs.GetParticles(rs.colorParticles); err != nil {
+		return err
+	}
+	rs.colorRange = rs.coloring.autoRange(len(rs.colorParticles), func(i int) float32 {
+		return rs.coloring.Field.value(rs.colorParticles[i])
+	})
+	return nil
+}
+
+// draws particles as points with technique
+func (rs *RenderState) drawPoints(t *core.Technique) error {
 
 	unbind := rs.vao.Bind()
 	defer unbind()
@@ -134,11 +441,44 @@ func (rs *RenderState) Render() {
 	unbind = rs.vbo.Bind(gl.ARRAY_BUFFER)
 	defer unbind()
 
-	detach := AttachVertexAttributes()
+	detach := rs.layout.attachVertexAttributes()
 	defer detach()
 
-	disable := rs.renderTechnique.Enable()
+	// 3D points are drawn in order of depth, 2D ones in order of particles
+	if rs.layout == &layout3D {
+		gl.Enable(gl.DEPTH_TEST)
+		defer gl.Disable(gl.DEPTH_TEST)
+	}
+
+	disable, err := t.Enable()
+	if err != nil {
+		return err
+	}
 	defer disable()
 
 	gl.DrawArrays(gl.POINTS, 0, int32(rs.countParticles))
+	return core.GetError()
+}
+
+func (rs *RenderState) Render() error {
+	if rs.renderMode == RENDER_FLUID {
+		return rs.measure("render_fluid", func() error {
+			return rs.fluidRenderer.Render(rs)
+		})
+	}
+	if err := rs.applyColoring(); err != nil {
+		return err
+	}
+	var viewport [4]int32
+	gl.GetIntegerv(gl.VIEWPORT, &viewport[0])
+	if rs.layout == &layout3D {
+		if err := rs.camera.apply(rs.renderTechnique, viewport[2], viewport[3]); err != nil {
+			return err
+		}
+	} else if err := rs.orthoCamera.apply(rs.renderTechnique, viewport[2], viewport[3]); err != nil {
+		return err
+	}
+	return rs.measure("render_points", func() error {
+		return rs.drawPoints(rs.renderTechnique)
+	})
 }
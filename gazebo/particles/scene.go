@@ -0,0 +1,736 @@
+package particles
+
+import "encoding/json"
+import "fmt"
+import "math"
+import "math/rand"
+import "os"
+import "path/filepath"
+import "strings"
+import "time"
+import "github.com/dmarychev/gazebo/core"
+import "github.com/dmarychev/gazebo/inspect"
+
+// Physical parameters of SPH system, every parameter is passed
+// to all techniques declaring the corresponding uniform
+type SceneParameters struct {
+	SmoothingRadius     float32 `json:"smoothing_radius"`     // uniform h
+	MaxNeighbors        uint32  `json:"max_neighbors"`        // uniform index_max_neighbors
+	Viscosity           float32 `json:"viscosity"`            // uniform mu
+	Gravity             float32 `json:"gravity"`              // uniform g
+	PressureCoefficient float32 `json:"pressure_coefficient"` // uniform k
+	TimeStep            float32 `json:"time_step"`            // uniform dt
+	Damping             float32 `json:"damping"`              // default damping of reflecting walls
+}
+
+func DefaultSceneParameters() SceneParameters {
+	return SceneParameters{
+		SmoothingRadius:     0.01,
+		MaxNeighbors:        40,
+		Viscosity:           5.0,
+		Gravity:             0.08,
+		PressureCoefficient: 0.015,
+		TimeStep:            0.01,
+		Damping:             -0.99,
+	}
+}
+
+func (sp *SceneParameters) uniforms() map[string]interface{} {
+	return map[string]interface{}{
+		"h":                   sp.SmoothingRadius,
+		"index_max_neighbors": sp.MaxNeighbors,
+		"mu":                  sp.Viscosity,
+		"g":                   sp.Gravity,
+		"k":                   sp.PressureCoefficient,
+		"dt":                  sp.TimeStep,
+	}
+}
+
+// sets parameters to uniforms declared by technique
+func (sp *SceneParameters) Apply(t *core.Technique) error {
+	tinfo, err := inspect.InspectTechnique(t)
+	if err != nil {
+		return err
+	}
+
+	uniforms := sp.uniforms()
+	for _, ui := range tinfo.UniformVariables {
+		switch value := uniforms[ui.Name].(type) {
+		case float32:
+			err = t.SetUniformFloat32(ui.Name, value)
+		case uint32:
+			err = t.SetUniformUint(ui.Name, value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Shader files of render technique
+type RenderDesc struct {
+	VertexShader   string        `json:"vertex_shader"`
+	FragmentShader string        `json:"fragment_shader"`
+	Fluid          *FluidDesc    `json:"fluid"`    // optional fluid surface rendering
+	Coloring       *ColoringDesc `json:"coloring"` // optional coloring of points by scalar field
+	Camera         *CameraDesc   `json:"camera"`   // camera of 3D scene
+	View           *ViewDesc     `json:"view"`     // orthographic camera of 2D scene
+}
+
+// Orthographic camera of 2D scene, omitted fields are those of DefaultOrthoCamera
+type ViewDesc struct {
+	Center *core.Vec2 `json:"center"`
+	Height float32    `json:"height"` // height of visible region
+}
+
+func (vd *ViewDesc) orthoCamera() OrthoCamera {
+	c := DefaultOrthoCamera()
+	if vd.Center != nil {
+		c.Center = *vd.Center
+	}
+	if vd.Height > 0 {
+		c.Height = vd.Height
+	}
+	return c
+}
+
+// Perspective camera of 3D scene, omitted fields are those of DefaultCamera
+type CameraDesc struct {
+	Eye    *core.Vec3 `json:"eye"`
+	Center *core.Vec3 `json:"center"`
+	Up     *core.Vec3 `json:"up"`
+	FovY   float32    `json:"fov_y"` // in degrees
+	Near   float32    `json:"near"`
+	Far    float32    `json:"far"`
+}
+
+func (cd *CameraDesc) camera() Camera {
+	c := DefaultCamera()
+	for _, v := range []struct {
+		dst *core.Vec3
+		src *core.Vec3
+	}{{&c.Eye, cd.Eye}, {&c.Center, cd.Center}, {&c.Up, cd.Up}} {
+		if v.src != nil {
+			*v.dst = *v.src
+		}
+	}
+	for _, v := range []struct {
+		dst *float32
+		src float32
+	}{{&c.FovY, cd.FovY}, {&c.Near, cd.Near}, {&c.Far, cd.Far}} {
+		if v.src > 0 {
+			*v.dst = v.src
+		}
+	}
+	return c
+}
+
+// Coloring of points: field is one of "none", "speed", "density", "pressure", "force"
+// and "mass", colormap is "viridis" (default) or "coolwarm", range of values mapped
+// to colormap is calculated from particles every AUTO_RANGE_INTERVAL if omitted
+type ColoringDesc struct {
+	Field    string     `json:"field"`
+	Colormap string     `json:"colormap"`
+	Range    *core.Vec2 `json:"range"` // {"x": min, "y": max}
+}
+
+func (cd *ColoringDesc) coloring() (Coloring, error) {
+	c := Coloring{AutoRange: cd.Range == nil}
+	var err error
+	if c.Field, err = ParseColorField(cd.Field); err != nil {
+		return c, err
+	}
+	if cd.Colormap != "" {
+		if c.Map, err = ParseColormap(cd.Colormap); err != nil {
+			return c, err
+		}
+	}
+	if cd.Range != nil {
+		c.Range = *cd.Range
+	}
+	return c, nil
+}
+
+// Shader files and parameters of FluidRenderer passes
+type FluidDesc struct {
+	SplatVertexShader   string  `json:"splat_vertex_shader"`
+	SplatFragmentShader string  `json:"splat_fragment_shader"`
+	ScreenVertexShader  string  `json:"screen_vertex_shader"` // fullscreen triangle of blur and shade passes
+	BlurFragmentShader  string  `json:"blur_fragment_shader"`
+	ShadeFragmentShader string  `json:"shade_fragment_shader"`
+	ParticleRadius      float32 `json:"particle_radius"`
+	BlurRadius          int32   `json:"blur_radius"` // in pixels
+}
+
+// Wall of simulation domain: "reflect", "periodic" or "open", damping multiplies
+// velocity of reflected particles and defaults to damping of scene parameters
+type WallDesc struct {
+	Type    string   `json:"type"`
+	Damping *float32 `json:"damping"`
+}
+
+// Simulation domain, omitted corners and walls are those of DefaultBoundaries
+// or DefaultBoundaries3, z of corners and back and front walls are used by 3D scenes
+type BoundariesDesc struct {
+	Min    *core.Vec3 `json:"min"`
+	Max    *core.Vec3 `json:"max"`
+	Left   *WallDesc  `json:"left"`
+	Right  *WallDesc  `json:"right"`
+	Bottom *WallDesc  `json:"bottom"`
+	Top    *WallDesc  `json:"top"`
+	Back   *WallDesc  `json:"back"`
+	Front  *WallDesc  `json:"front"`
+}
+
+// sets described walls
+func (bd *BoundariesDesc) walls(walls []Wall) error {
+	for i, wd := range []*WallDesc{bd.Left, bd.Right, bd.Bottom, bd.Top, bd.Back, bd.Front}[:len(walls)] {
+		if wd == nil {
+			continue
+		}
+		behavior, err := ParseWallBehavior(wd.Type)
+		if err != nil {
+			return fmt.Errorf("%v wall: %v", wallNames[i], err)
+		}
+		walls[i].Behavior = behavior
+		if wd.Damping != nil {
+			walls[i].Damping, walls[i].SceneDamping = *wd.Damping, false
+		}
+	}
+	return nil
+}
+
+// boundaries described by 2D scene
+func (sc *Scene) boundaries() (Boundaries, error) {
+	b := DefaultBoundaries(sc.Parameters.Damping)
+	bd := sc.Boundaries
+	if bd == nil {
+		return b, nil
+	}
+	if bd.Back != nil || bd.Front != nil {
+		return b, fmt.Errorf("back and front walls require 3D scene")
+	}
+	if bd.Min != nil {
+		b.Min = core.Vec2{X: bd.Min.X, Y: bd.Min.Y}
+	}
+	if bd.Max != nil {
+		b.Max = core.Vec2{X: bd.Max.X, Y: bd.Max.Y}
+	}
+	if err := bd.walls(b.Walls[:]); err != nil {
+		return b, err
+	}
+	return b, b.Validate()
+}
+
+// boundaries described by 3D scene
+func (sc *Scene) boundaries3() (Boundaries3, error) {
+	b := DefaultBoundaries3(sc.Parameters.Damping)
+	bd := sc.Boundaries
+	if bd == nil {
+		return b, nil
+	}
+	if bd.Min != nil {
+		b.Min = *bd.Min
+	}
+	if bd.Max != nil {
+		b.Max = *bd.Max
+	}
+	if err := bd.walls(b.Walls[:]); err != nil {
+		return b, err
+	}
+	return b, b.Validate()
+}
+
+// sets boundaries described by scene of its dimensions to technique
+func (sc *Scene) applyBoundaries(t *core.Technique) error {
+	if sc.dimensions() == 3 {
+		b, err := sc.boundaries3()
+		if err != nil {
+			return err
+		}
+		return b.Apply(t)
+	}
+	b, err := sc.boundaries()
+	if err != nil {
+		return err
+	}
+	return b.Apply(t)
+}
+
+// Static obstacle: "circle" of radius around center, "segment" between two vertices
+// of thickness 2*radius or closed "polygon" of vertices, see Obstacle
+type ObstacleDesc struct {
+	Type        string      `json:"type"`
+	Center      core.Vec2   `json:"center"`
+	Radius      float32     `json:"radius"`
+	Vertices    []core.Vec2 `json:"vertices"`
+	Restitution float32     `json:"restitution"`
+	Friction    float32     `json:"friction"`
+}
+
+var obstacleTypes = map[string]ObstacleType{
+	"circle":  OBSTACLE_CIRCLE,
+	"segment": OBSTACLE_SEGMENT,
+	"polygon": OBSTACLE_POLYGON,
+}
+
+// obstacles described by scene
+func (sc *Scene) obstacles() ([]Obstacle, error) {
+	obstacles := make([]Obstacle, len(sc.Obstacles))
+	for i, od := range sc.Obstacles {
+		ty, ok := obstacleTypes[od.Type]
+		if !ok {
+			return nil, fmt.Errorf("unknown obstacle type %q", od.Type)
+		}
+		obstacles[i] = Obstacle{
+			Type:        ty,
+			Center:      od.Center,
+			Radius:      od.Radius,
+			Vertices:    od.Vertices,
+			Restitution: od.Restitution,
+			Friction:    od.Friction,
+		}
+	}
+	return obstacles, nil
+}
+
+// Neighbor search stage, `Shaders` maps stage names to compute shader files:
+// "update" and "clear" for "brute_force" method,
+// "clear", "count", "prefix_sum", "sort" and "neighbors" for "grid" method
+type NeighborSearchDesc struct {
+	Method    string            `json:"method"`
+	GridCells uint32            `json:"grid_cells"` // number of hash table cells for "grid" method
+	Shaders   map[string]string `json:"shaders"`
+}
+
+// Initial particles generator
+//   - "block" places Columns x Rows (x Layers in 3D) particles starting from Origin with Spacing
+//   - "circle" places particles of a regular grid with Spacing inside circle (ball in 3D) of Radius around Origin
+//   - "random" places Count particles uniformly inside rectangle (box in 3D) of Size starting from Origin
+//
+// Every particle is displaced randomly by at most Jitter along each axis.
+// Z components of vectors are used only by 3D scenes.
+type Emitter struct {
+	Type     string    `json:"type"`
+	Origin   core.Vec3 `json:"origin"`
+	Size     core.Vec3 `json:"size"`
+	Columns  int       `json:"columns"`
+	Rows     int       `json:"rows"`
+	Layers   int       `json:"layers"` // along z axis
+	Radius   float32   `json:"radius"`
+	Count    int       `json:"count"`
+	Spacing  float32   `json:"spacing"`
+	Jitter   float32   `json:"jitter"`
+	Mass     float32   `json:"mass"`
+	Velocity core.Vec3 `json:"velocity"`
+}
+
+func (e *Emitter) emit(particles []Particle, rng *rand.Rand) ([]Particle, error) {
+	add := func(x, y float32) {
+		particles = append(particles, Particle{
+			R: core.Vec2{
+				X: x - e.Jitter + 2*e.Jitter*rng.Float32(),
+				Y: y - e.Jitter + 2*e.Jitter*rng.Float32(),
+			},
+			V: core.Vec2{X: e.Velocity.X, Y: e.Velocity.Y},
+			M: e.Mass,
+		})
+	}
+
+	switch e.Type {
+	case "block":
+		for i := 0; i < e.Columns; i++ {
+			for j := 0; j < e.Rows; j++ {
+				add(e.Origin.X+e.Spacing*float32(i), e.Origin.Y+e.Spacing*float32(j))
+			}
+		}
+	case "circle":
+		if e.Spacing <= 0 {
+			return nil, fmt.Errorf("circle emitter requires positive spacing, got %v", e.Spacing)
+		}
+		n := int(math.Floor(float64(e.Radius / e.Spacing)))
+		for i := -n; i <= n; i++ {
+			for j := -n; j <= n; j++ {
+				x, y := e.Spacing*float32(i), e.Spacing*float32(j)
+				if x*x+y*y <= e.Radius*e.Radius {
+					add(e.Origin.X+x, e.Origin.Y+y)
+				}
+			}
+		}
+	case "random":
+		for i := 0; i < e.Count; i++ {
+			add(e.Origin.X+e.Size.X*rng.Float32(), e.Origin.Y+e.Size.Y*rng.Float32())
+		}
+	default:
+		return nil, fmt.Errorf("unknown emitter type %q", e.Type)
+	}
+	return particles, nil
+}
+
+func (e *Emitter) emit3(particles []Particle3, rng *rand.Rand) ([]Particle3, error) {
+	jitter := func() float32 {
+		return -e.Jitter + 2*e.Jitter*rng.Float32()
+	}
+	add := func(x, y, z float32) {
+		particles = append(particles, Particle3{
+			R: core.Vec3{X: x + jitter(), Y: y + jitter(), Z: z + jitter()},
+			V: e.Velocity,
+			M: e.Mass,
+		})
+	}
+
+	switch e.Type {
+	case "block":
+		for i := 0; i < e.Columns; i++ {
+			for j := 0; j < e.Rows; j++ {
+				for k := 0; k < e.Layers; k++ {
+					add(e.Origin.X+e.Spacing*float32(i), e.Origin.Y+e.Spacing*float32(j), e.Origin.Z+e.Spacing*float32(k))
+				}
+			}
+		}
+	case "circle":
+		if e.Spacing <= 0 {
+			return nil, fmt.Errorf("circle emitter requires positive spacing, got %v", e.Spacing)
+		}
+		n := int(math.Floor(float64(e.Radius / e.Spacing)))
+		for i := -n; i <= n; i++ {
+			for j := -n; j <= n; j++ {
+				for k := -n; k <= n; k++ {
+					x, y, z := e.Spacing*float32(i), e.Spacing*float32(j), e.Spacing*float32(k)
+					if x*x+y*y+z*z <= e.Radius*e.Radius {
+						add(e.Origin.X+x, e.Origin.Y+y, e.Origin.Z+z)
+					}
+				}
+			}
+		}
+	case "random":
+		for i := 0; i < e.Count; i++ {
+			add(e.Origin.X+e.Size.X*rng.Float32(), e.Origin.Y+e.Size.Y*rng.Float32(), e.Origin.Z+e.Size.Z*rng.Float32())
+		}
+	default:
+		return nil, fmt.Errorf("unknown emitter type %q", e.Type)
+	}
+	return particles, nil
+}
+
+// Declarative description of particle system, see scenes/*.json
+type Scene struct {
+	Parameters     SceneParameters    `json:"parameters"`
+	Render         RenderDesc         `json:"render"`
+	NeighborSearch NeighborSearchDesc `json:"neighbor_search"`
+	Pipeline       []string           `json:"pipeline"` // update techniques' compute shader files in order of execution
+	Emitters       []Emitter          `json:"emitters"`
+	Seed           int64              `json:"seed"`    // random seed of emitters, 0 means seed from current time
+	Backend        string             `json:"backend"` // "gpu" (default) or "cpu" to run pipeline on CPUSolver
+	Boundaries     *BoundariesDesc    `json:"boundaries"`
+	Obstacles      []ObstacleDesc     `json:"obstacles"`  // obstacles of collide_obstacles stage
+	Dimensions     int                `json:"dimensions"` // 2 (default) or 3, shaders must declare Particle or Particle3 layout
+
+	dir string // directory of scene file, shader files are relative to it
+}
+
+// loads scene from JSON file
+func LoadScene(sceneFile string) (*Scene, error) {
+	f, err := os.Open(sceneFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sc := Scene{
+		Parameters:     DefaultSceneParameters(),
+		NeighborSearch: NeighborSearchDesc{GridCells: 65536},
+		Dimensions:     2,
+		dir:            filepath.Dir(sceneFile),
+	}
+
+	decoder := json.NewDecoder(f)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&sc); err != nil {
+		return nil, fmt.Errorf("failed to load scene %v: %v", sceneFile, err)
+	}
+
+	return &sc, nil
+}
+
+// dimensions of particles, scenes created without LoadScene are 2D by default
+func (sc *Scene) dimensions() int {
+	if sc.Dimensions == 0 {
+		return 2
+	}
+	return sc.Dimensions
+}
+
+func (sc *Scene) path(file string) string {
+	if filepath.IsAbs(file) {
+		return file
+	}
+	return filepath.Join(sc.dir, file)
+}
+
+func (sc *Scene) newComputeTechnique(shaderFile string) (*core.Technique, error) {
+	technique, err := NewComputeTechniqueFromFile(sc.path(shaderFile))
+	if err != nil {
+		return nil, err
+	}
+	dimensions, err := particleDimensions(technique)
+	if err == nil && dimensions != 0 && dimensions != sc.dimensions() {
+		err = fmt.Errorf("%v: particles are %vD, scene is %vD", shaderFile, dimensions, sc.dimensions())
+	}
+	if err == nil {
+		err = sc.Parameters.Apply(technique)
+	}
+	if err == nil {
+		err = sc.applyBoundaries(technique)
+	}
+	if err != nil {
+		deleteTechnique(technique)
+		return nil, err
+	}
+	return technique, nil
+}
+
+func (sc *Scene) newNeighborSearch() (NeighborSearch, error) {
+	var stages []string
+	switch sc.NeighborSearch.Method {
+	case "brute_force":
+		stages = []string{"update", "clear"}
+	case "grid":
+		stages = []string{"clear", "count", "prefix_sum", "sort", "neighbors"}
+	default:
+		return nil, fmt.Errorf("unknown neighbor search method %q", sc.NeighborSearch.Method)
+	}
+
+	techniques := make([]*core.Technique, len(stages))
+	for i, stage := range stages {
+		shaderFile, ok := sc.NeighborSearch.Shaders[stage]
+		if !ok {
+			deleteTechniques(techniques)
+			return nil, fmt.Errorf("no shader for %q stage of %v neighbor search", stage, sc.NeighborSearch.Method)
+		}
+		technique, err := sc.newComputeTechnique(shaderFile)
+		if err != nil {
+			deleteTechniques(techniques)
+			return nil, err
+		}
+		techniques[i] = technique
+	}
+
+	if sc.NeighborSearch.Method == "brute_force" {
+		return NewBruteForceNeighborSearch(techniques[0], techniques[1]), nil
+	}
+	gridSearch, err := NewGridNeighborSearch(techniques[0], techniques[1], techniques[2], techniques[3], techniques[4], sc.NeighborSearch.GridCells)
+	if err != nil {
+		deleteTechniques(techniques)
+		return nil, err
+	}
+	return gridSearch, nil
+}
+
+// creates particles system described by scene, without particles
+func (sc *Scene) NewSystem() (*System, error) {
+	if _, err := particleLayoutOf(sc.dimensions()); err != nil {
+		return nil, err
+	}
+	render, err := NewRenderTechniqueFromFile(sc.path(sc.Render.VertexShader), sc.path(sc.Render.FragmentShader))
+	if err != nil {
+		return nil, err
+	}
+	if err = sc.Parameters.Apply(render); err != nil {
+		deleteTechnique(render)
+		return nil, err
+	}
+
+	s, err := sc.newSystem(render)
+	if err != nil {
+		deleteTechnique(render)
+		return nil, err
+	}
+
+	if err = sc.setupSystem(s); err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// adds fluid renderer, obstacles, coloring and camera of scene to system
+func (sc *Scene) setupSystem(s *System) error {
+	s.parameters = sc.Parameters
+	if sc.dimensions() == 3 {
+		b, err := sc.boundaries3()
+		if err != nil {
+			return err
+		}
+		s.walls = b.Walls[:]
+	} else {
+		b, err := sc.boundaries()
+		if err != nil {
+			return err
+		}
+		s.walls = b.Walls[:]
+	}
+
+	if fluid := sc.Render.Fluid; fluid != nil {
+		if sc.dimensions() != 2 {
+			return fmt.Errorf("fluid rendering supports only 2D scenes")
+		}
+		fr, err := sc.newFluidRenderer(fluid)
+		if err != nil {
+			return err
+		}
+		s.SetFluidRenderer(fr)
+	}
+
+	obstacles, err := sc.obstacles()
+	if err != nil {
+		return err
+	}
+	if err = s.SetObstacles(obstacles); err != nil {
+		return err
+	}
+
+	if cd := sc.Render.Coloring; cd != nil {
+		c, err := cd.coloring()
+		if err != nil {
+			return err
+		}
+		s.SetColoring(c)
+	}
+
+	if cd := sc.Render.Camera; cd != nil {
+		s.SetCamera(cd.camera())
+	}
+	if vd := sc.Render.View; vd != nil {
+		s.SetOrthoCamera(vd.orthoCamera())
+	}
+	return nil
+}
+
+func (sc *Scene) newFluidRenderer(fluid *FluidDesc) (*FluidRenderer, error) {
+	shaders := [][2]string{
+		{fluid.SplatVertexShader, fluid.SplatFragmentShader},
+		{fluid.ScreenVertexShader, fluid.BlurFragmentShader},
+		{fluid.ScreenVertexShader, fluid.ShadeFragmentShader},
+	}
+	techniques := make([]*core.Technique, len(shaders))
+	for i, shader := range shaders {
+		technique, err := NewRenderTechniqueFromFile(sc.path(shader[0]), sc.path(shader[1]))
+		if err != nil {
+			deleteTechniques(techniques)
+			return nil, err
+		}
+		techniques[i] = technique
+	}
+
+	fr := NewFluidRenderer(techniques[0], techniques[1], techniques[2])
+	if fluid.ParticleRadius > 0 {
+		fr.ParticleRadius = fluid.ParticleRadius
+	}
+	if fluid.BlurRadius > 0 {
+		fr.BlurRadius = fluid.BlurRadius
+	}
+	return fr, nil
+}
+
+// creates system of scene's backend rendered with `render` technique,
+// system owns `render` unless error is returned
+func (sc *Scene) newSystem(render *core.Technique) (*System, error) {
+	switch sc.Backend {
+	case "", "gpu":
+	case "cpu":
+		return sc.newCPUSystem(render)
+	default:
+		return nil, fmt.Errorf("unknown backend %q", sc.Backend)
+	}
+
+	neighborSearch, err := sc.newNeighborSearch()
+	if err != nil {
+		return nil, err
+	}
+
+	pipeline := make([]*core.Technique, len(sc.Pipeline))
+	for i, shaderFile := range sc.Pipeline {
+		technique, err := sc.newComputeTechnique(shaderFile)
+		if err != nil {
+			deleteTechniques(pipeline)
+			neighborSearch.Delete()
+			return nil, err
+		}
+		pipeline[i] = technique
+	}
+
+	newSystem := NewSystem
+	if sc.dimensions() == 3 {
+		newSystem = NewSystem3
+	}
+	s := newSystem(render, neighborSearch, sc.Parameters.MaxNeighbors)
+	for _, technique := range pipeline {
+		s.AddUpdateTechnique(technique)
+	}
+	return s, nil
+}
+
+// pipeline stages are named after shader files
+func (sc *Scene) newCPUSystem(render *core.Technique) (*System, error) {
+	if sc.dimensions() != 2 {
+		return nil, fmt.Errorf("cpu backend supports only 2D scenes")
+	}
+	stages := make([]string, len(sc.Pipeline))
+	for i, shaderFile := range sc.Pipeline {
+		stages[i] = strings.TrimSuffix(filepath.Base(shaderFile), filepath.Ext(shaderFile))
+	}
+	boundaries, err := sc.boundaries()
+	if err != nil {
+		return nil, err
+	}
+	solver, err := NewCPUSolver(sc.Parameters, boundaries, stages)
+	if err != nil {
+		return nil, err
+	}
+	return NewCPUSystem(render, solver), nil
+}
+
+// random generator of emitters seeded with scene's seed
+func (sc *Scene) rand() *rand.Rand {
+	seed := sc.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return rand.New(rand.NewSource(seed))
+}
+
+// generates initial particles with emitters of 2D scene
+func (sc *Scene) Particles() ([]Particle, error) {
+	if sc.dimensions() != 2 {
+		return nil, fmt.Errorf("scene is %vD, its particles are generated by Particles3", sc.dimensions())
+	}
+	rng := sc.rand()
+
+	particles := make([]Particle, 0)
+	for i := range sc.Emitters {
+		var err error
+		if particles, err = sc.Emitters[i].emit(particles, rng); err != nil {
+			return nil, err
+		}
+	}
+	return particles, nil
+}
+
+// generates initial particles with emitters of 3D scene
+func (sc *Scene) Particles3() ([]Particle3, error) {
+	if sc.dimensions() != 3 {
+		return nil, fmt.Errorf("scene is %vD, its particles are generated by Particles", sc.dimensions())
+	}
+	rng := sc.rand()
+
+	particles := make([]Particle3, 0)
+	for i := range sc.Emitters {
+		var err error
+		if particles, err = sc.Emitters[i].emit3(particles, rng); err != nil {
+			return nil, err
+		}
+	}
+	return particles, nil
+}
@@ -0,0 +1,115 @@
+package particles
+
+import "encoding/binary"
+import "errors"
+import "fmt"
+import "hash/fnv"
+import "io"
+import "math"
+import "reflect"
+
+const (
+	SNAPSHOT_MAGIC   = "GZSN" // the first bytes of snapshot
+	SNAPSHOT_VERSION = 1      // version of snapshot format written by WriteSnapshot
+	SNAPSHOT_CHUNK   = 4096   // particles allocated before reading, more are allocated as they are read
+)
+
+var ErrSnapshotFormat = errors.New("invalid snapshot")
+
+// Header of snapshot, written in little endian byte order and followed by
+// CountParticles records of particles' float32 fields in order of declaration
+type SnapshotHeader struct {
+	Magic          [4]byte         // SNAPSHOT_MAGIC
+	Version        uint32          // SNAPSHOT_VERSION
+	CountParticles uint32          // number of particle records
+	LayoutHash     uint64          // ParticleLayoutHash of writer
+	Time           float64         // simulation time
+	Parameters     SceneParameters // parameters of simulation
+}
+
+// hash of names, types and offsets of Particle fields, snapshots of
+// particles of different layout are rejected
+func ParticleLayoutHash() uint64 {
+	h := fnv.New64a()
+	t := reflect.TypeOf(Particle{})
+	fmt.Fprintf(h, "%v:", t.Size())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fmt.Fprintf(h, "%v %v %v;", field.Name, field.Type, field.Offset)
+	}
+	return h.Sum64()
+}
+
+// fields of particle stored in snapshot, padding is skipped
+func particleFields(p *Particle) []*float32 {
+	return []*float32{
+		&p.R.X, &p.R.Y,
+		&p.V.X, &p.V.Y,
+		&p.F.X, &p.F.Y,
+		&p.prevF.X, &p.prevF.Y,
+		&p.P, &p.D, &p.M,
+	}
+}
+
+// writes snapshot of particles, header's magic, version, number of particles
+// and layout hash are filled in
+func WriteSnapshot(w io.Writer, header SnapshotHeader, particles []Particle) error {
+	copy(header.Magic[:], SNAPSHOT_MAGIC)
+	header.Version = SNAPSHOT_VERSION
+	header.CountParticles = uint32(len(particles))
+	header.LayoutHash = ParticleLayoutHash()
+	if err := binary.Write(w, binary.LittleEndian, &header); err != nil {
+		return err
+	}
+
+	countFields := len(particleFields(&Particle{}))
+	record := make([]byte, 4*countFields)
+	for i := range particles {
+		for j, field := range particleFields(&particles[i]) {
+			binary.LittleEndian.PutUint32(record[4*j:], math.Float32bits(*field))
+		}
+		if _, err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reads snapshot written by WriteSnapshot, returns error wrapping ErrSnapshotFormat
+// if it's not a snapshot, has unsupported version or different particle layout
+func ReadSnapshot(r io.Reader) (*SnapshotHeader, []Particle, error) {
+	var header SnapshotHeader
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, nil, fmt.Errorf("%w: header: %v", ErrSnapshotFormat, err)
+	}
+	if string(header.Magic[:]) != SNAPSHOT_MAGIC {
+		return nil, nil, fmt.Errorf("%w: magic %q", ErrSnapshotFormat, header.Magic[:])
+	}
+	if header.Version != SNAPSHOT_VERSION {
+		return nil, nil, fmt.Errorf("%w: version %v, supported version %v", ErrSnapshotFormat, header.Version, SNAPSHOT_VERSION)
+	}
+	if header.LayoutHash != ParticleLayoutHash() {
+		return nil, nil, fmt.Errorf("%w: particle layout hash %x, expected %x", ErrSnapshotFormat, header.LayoutHash, ParticleLayoutHash())
+	}
+
+	// slice grows as records are read, so a short file fails before the whole count
+	// is allocated
+	count := int(header.CountParticles)
+	capacity := count
+	if capacity > SNAPSHOT_CHUNK {
+		capacity = SNAPSHOT_CHUNK
+	}
+	particles := make([]Particle, 0, capacity)
+	countFields := len(particleFields(&Particle{}))
+	record := make([]byte, 4*countFields)
+	for i := 0; i < count; i++ {
+		if _, err := io.ReadFull(r, record); err != nil {
+			return nil, nil, fmt.Errorf("%w: particle %v of %v: %v", ErrSnapshotFormat, i, count, err)
+		}
+		particles = append(particles, Particle{})
+		for j, field := range particleFields(&particles[i]) {
+			*field = math.Float32frombits(binary.LittleEndian.Uint32(record[4*j:]))
+		}
+	}
+	return &header, particles, nil
+}
@@ -0,0 +1,109 @@
+package particles
+
+import "bytes"
+import "encoding/binary"
+import "errors"
+import "runtime"
+import "testing"
+import "github.com/dmarychev/gazebo/core"
+
+// offsets of header fields in snapshot, binary.Write packs them without padding
+const (
+	SNAPSHOT_VERSION_OFFSET = 4
+	SNAPSHOT_COUNT_OFFSET   = 8
+	SNAPSHOT_HASH_OFFSET    = 12
+)
+
+func testSnapshot(t *testing.T) (SnapshotHeader, []Particle, []byte) {
+	t.Helper()
+	header := SnapshotHeader{Time: 1.25, Parameters: SceneParameters{MaxNeighbors: 32}}
+	particles := make([]Particle, 5)
+	for i := range particles {
+		f := float32(i)
+		particles[i] = Particle{
+			R: core.Vec2{X: f, Y: -f}, V: core.Vec2{X: 0.5 * f, Y: 2},
+			F: core.Vec2{X: 3, Y: f}, prevF: core.Vec2{X: -1, Y: 1 / (f + 1)},
+			P: 100 + f, D: 1000 - f, M: 0.01,
+		}
+	}
+	var buf bytes.Buffer
+	if err := WriteSnapshot(&buf, header, particles); err != nil {
+		t.Fatal(err)
+	}
+	return header, particles, buf.Bytes()
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	header, particles, data := testSnapshot(t)
+	h, ps, err := ReadSnapshot(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Time != header.Time || h.Parameters != header.Parameters || h.CountParticles != uint32(len(particles)) {
+		t.Errorf("header is %+v", h)
+	}
+	if len(ps) != len(particles) {
+		t.Fatalf("got %v particles, want %v", len(ps), len(particles))
+	}
+	for i := range ps {
+		if ps[i] != particles[i] {
+			t.Errorf("particle %v is %+v, want %+v", i, ps[i], particles[i])
+		}
+	}
+
+	// empty snapshot
+	var buf bytes.Buffer
+	if err := WriteSnapshot(&buf, header, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, ps, err := ReadSnapshot(&buf); err != nil || len(ps) != 0 {
+		t.Errorf("empty snapshot: %v particles, %v", len(ps), err)
+	}
+}
+
+func TestSnapshotInvalid(t *testing.T) {
+	_, _, data := testSnapshot(t)
+	corrupt := func(offset int, value []byte) []byte {
+		d := append([]byte(nil), data...)
+		copy(d[offset:], value)
+		return d
+	}
+	u32 := func(v uint32) []byte { return binary.LittleEndian.AppendUint32(nil, v) }
+	u64 := func(v uint64) []byte { return binary.LittleEndian.AppendUint64(nil, v) }
+
+	cases := []struct {
+		name string
+		data []byte
+	}{
+		{"magic", corrupt(0, []byte("GZSX"))},
+		{"version", corrupt(SNAPSHOT_VERSION_OFFSET, u32(SNAPSHOT_VERSION+1))},
+		{"layout hash", corrupt(SNAPSHOT_HASH_OFFSET, u64(ParticleLayoutHash()+1))},
+		{"truncated header", data[:SNAPSHOT_HASH_OFFSET]},
+		{"truncated particles", data[:len(data)-1]},
+		{"count beyond particles", corrupt(SNAPSHOT_COUNT_OFFSET, u32(6))},
+		{"empty", nil},
+	}
+	for _, c := range cases {
+		if _, _, err := ReadSnapshot(bytes.NewReader(c.data)); !errors.Is(err, ErrSnapshotFormat) {
+			t.Errorf("%v: got %v, want ErrSnapshotFormat", c.name, err)
+		}
+	}
+}
+
+// count of corrupted header isn't allocated before particles are read
+func TestSnapshotHugeCount(t *testing.T) {
+	_, _, data := testSnapshot(t)
+	d := append([]byte(nil), data...)
+	binary.LittleEndian.PutUint32(d[SNAPSHOT_COUNT_OFFSET:], 0xFFFFFFFF)
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, _, err := ReadSnapshot(bytes.NewReader(d))
+	runtime.ReadMemStats(&after)
+	if !errors.Is(err, ErrSnapshotFormat) {
+		t.Errorf("got %v, want ErrSnapshotFormat", err)
+	}
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 1<<20 {
+		t.Errorf("allocated %v bytes for %v particles in file", allocated, 5)
+	}
+}
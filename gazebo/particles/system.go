@@ -1,7 +1,9 @@
 package particles
 
+import "fmt"
+import "io"
 import "log"
-import "io/ioutil"
+import "reflect"
 import "github.com/dmarychev/gazebo/core"
 import "github.com/dmarychev/gazebo/inspect"
 
@@ -18,20 +20,67 @@ type Particle struct {
 }
 
 type System struct {
-	renderState *RenderState // objects related to rendering
+	renderState *RenderState    // objects related to rendering
+	solver      *CPUSolver      // updates particles on CPU instead of update techniques, if set
+	particles   []Particle      // particles' state updated by solver
+	parameters  SceneParameters // parameters set to techniques, TimeStep advances time
+	walls       []Wall          // walls set to techniques and solver by scene, nil without scene
+	time        float64         // simulation time, sum of time steps of updates
 }
 
-func NewSystem(renderTechnique, indexUpdate, indexClear *core.Technique, indexMaxNeighbors uint32) *System {
+func NewSystem(renderTechnique *core.Technique, neighborSearch NeighborSearch, indexMaxNeighbors uint32) *System {
 
 	s := System{
-		renderState: NewRenderState(renderTechnique, indexUpdate, indexClear, indexMaxNeighbors),
+		renderState: NewRenderState(renderTechnique, neighborSearch, indexMaxNeighbors),
 	}
 
 	return &s
 }
 
-func (s *System) SetParticles(particles []Particle) {
-	s.renderState.SetParticles(particles)
+// creates system of 3D particles, its techniques must declare Particle3 layout
+func NewSystem3(renderTechnique *core.Technique, neighborSearch NeighborSearch, indexMaxNeighbors uint32) *System {
+	return &System{
+		renderState: NewRenderState3(renderTechnique, neighborSearch, indexMaxNeighbors),
+	}
+}
+
+// creates particle system updated by CPU solver, GPU is used only for rendering
+func NewCPUSystem(renderTechnique *core.Technique, solver *CPUSolver) *System {
+	return &System{
+		renderState: NewRenderState(renderTechnique, nil, 0),
+		solver:      solver,
+	}
+}
+
+func (s *System) SetParticles(particles []Particle) error {
+	if s.solver != nil {
+		s.particles = append(s.particles[:0], particles...)
+	}
+	return s.renderState.SetParticles(particles)
+}
+
+// sets particles of 3D system
+func (s *System) SetParticles3(particles []Particle3) error {
+	return s.renderState.SetParticles3(particles)
+}
+
+// number of dimensions of particles, 2 or 3
+func (s *System) Dimensions() int {
+	return s.renderState.Dimensions()
+}
+
+// sets static obstacles particles collide with in collide_obstacles stage,
+// must be called after all update techniques are added
+func (s *System) SetObstacles(obstacles []Obstacle) error {
+	for i := range obstacles {
+		if err := obstacles[i].Validate(); err != nil {
+			return err
+		}
+	}
+	if s.solver != nil {
+		s.solver.Obstacles = append([]Obstacle(nil), obstacles...)
+	}
+	return s.renderState.SetObstacles(obstacles)
 }
 
 func (s *System) AddUpdateTechniqueFromFile(compShaderFile string) (err error) {
@@ -47,70 +96,306 @@ func (s *System) AddUpdateTechnique(t *core.Technique) {
 	s.renderState.AddUpdateTechnique(t)
 }
 
+// renders fluid surface in RENDER_FLUID mode
+func (s *System) SetFluidRenderer(fr *FluidRenderer) {
+	s.renderState.SetFluidRenderer(fr)
+}
+
+// selects the way particles are rendered, RENDER_FLUID requires fluid renderer
+func (s *System) SetRenderMode(mode RenderMode) error {
+	return s.renderState.SetRenderMode(mode)
+}
+
+func (s *System) RenderMode() RenderMode {
+	return s.renderState.RenderMode()
+}
+
+// colors points by scalar field in RENDER_POINTS mode
+func (s *System) SetColoring(c Coloring) {
+	s.renderState.SetColoring(c)
+}
+
+func (s *System) Coloring() Coloring {
+	return s.renderState.Coloring()
+}
+
+// sets camera 3D particles are rendered with
+func (s *System) SetCamera(c Camera) {
+	s.renderState.SetCamera(c)
+}
+
+func (s *System) Camera() Camera {
+	return s.renderState.Camera()
+}
+
+// sets camera 2D particles are rendered with
+func (s *System) SetOrthoCamera(c OrthoCamera) {
+	s.renderState.SetOrthoCamera(c)
+}
+
+func (s *System) OrthoCamera() OrthoCamera {
+	return s.renderState.OrthoCamera()
+}
+
+// turns on measuring GPU time of update stages and rendering, CPU backend's
+// steps are not measured
+func (s *System) SetProfiling(enabled bool) {
+	s.renderState.SetProfiling(enabled)
+}
+
+// GPU times of stages averaged over the last PROFILE_WINDOW updates, nil if profiling is off
+func (s *System) StageTimes() []StageTime {
+	return s.renderState.StageTimes()
+}
+
 // shows current state on screen
-func (s *System) Render() {
-	s.renderState.Render()
-	core.CheckError()
+func (s *System) Render() error {
+	return s.renderState.Render()
 }
 
 // updates particle system's state
-func (s *System) Update() {
-	s.renderState.Update()
-	core.CheckError()
+func (s *System) Update() error {
+	s.time += float64(s.parameters.TimeStep)
+	if s.solver != nil {
+		s.solver.Step(s.particles)
+		return s.renderState.SetParticles(s.particles)
+	}
+	return s.renderState.Update()
 }
 
-// synchronizes `s.particles` with current state in GPU memory
-func (s *System) Sync() {
+// simulation time, advanced by TimeStep of parameters on every update
+func (s *System) Time() float64 {
+	return s.time
+}
 
+func (s *System) Parameters() SceneParameters {
+	return s.parameters
 }
 
+// sets parameters to all techniques declaring their uniforms and to CPU solver,
+// Damping is set to walls of scene which have no damping of their own;
+// MaxNeighbors can't differ from the one index is allocated for
+func (s *System) SetParameters(parameters SceneParameters) error {
+	rs := s.renderState
+	if rs.neighborSearch != nil && parameters.MaxNeighbors != rs.indexMaxNeighbors {
+		return fmt.Errorf("can't change max neighbors of index from %v to %v", rs.indexMaxNeighbors, parameters.MaxNeighbors)
+	}
+	for i := range s.walls {
+		if s.walls[i].SceneDamping {
+			s.walls[i].Damping = parameters.Damping
+		}
+	}
+	for _, t := range s.renderState.techniques() {
+		if err := parameters.Apply(t); err != nil {
+			return err
+		}
+		if s.walls != nil {
+			if err := applyWalls(t, s.walls); err != nil {
+				return err
+			}
+		}
+	}
+	if s.solver != nil {
+		s.solver.Parameters = parameters
+		copy(s.solver.Boundaries.Walls[:], s.walls)
+	}
+	s.parameters = parameters
+	return nil
+}
+
+// writes snapshot of particles' state, simulation time and parameters, see WriteSnapshot,
+// only 2D systems are supported
+func (s *System) SaveSnapshot(w io.Writer) error {
+	if s.Dimensions() != 2 {
+		return fmt.Errorf("snapshots of %vD systems are not supported", s.Dimensions())
+	}
+	particles, err := s.Particles()
+	if err != nil {
+		return err
+	}
+	return WriteSnapshot(w, SnapshotHeader{Time: s.time, Parameters: s.parameters}, particles)
+}
+
+// restores particles' state, simulation time and parameters from snapshot written by SaveSnapshot
+func (s *System) LoadSnapshot(r io.Reader) error {
+	if s.Dimensions() != 2 {
+		return fmt.Errorf("snapshots of %vD systems are not supported", s.Dimensions())
+	}
+	header, particles, err := ReadSnapshot(r)
+	if err != nil {
+		return err
+	}
+	if err = s.SetParameters(header.Parameters); err != nil {
+		return err
+	}
+	if err = s.SetParticles(particles); err != nil {
+		return err
+	}
+	s.time = header.Time
+	return nil
+}
+
+// recompiles techniques whose shader files changed since they were loaded,
+// must be called from the thread owning GL context, e.g. once per frame
+func (s *System) ReloadShaders() error {
+	for _, t := range s.renderState.techniques() {
+		if err := reloadTechnique(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// releases all GL objects owned by the system: techniques, buffers, textures
+// and framebuffers, system must not be used afterwards
+func (s *System) Close() error {
+	s.renderState.Delete()
+	s.solver, s.particles = nil, nil
+	return core.GetError()
+}
+
+// number of particles in the system
+func (s *System) CountParticles() int {
+	return int(s.renderState.CountParticles())
+}
+
+// synchronizes `particles` with current state in GPU memory,
+// `particles` must have exactly CountParticles() elements
+func (s *System) Sync(particles []Particle) error {
+	if len(particles) != s.CountParticles() {
+		return fmt.Errorf("Sync: got %v particles, system has %v", len(particles), s.CountParticles())
+	}
+	if s.solver != nil {
+		copy(particles, s.particles)
+		return nil
+	}
+	return s.renderState.GetParticles(particles)
+}
+
+// returns a copy of current particles' state in GPU memory
+func (s *System) Particles() ([]Particle, error) {
+	particles := make([]Particle, s.CountParticles())
+	if err := s.Sync(particles); err != nil {
+		return nil, err
+	}
+	return particles, nil
+}
+
+// synchronizes `particles` with current state of 3D system in GPU memory,
+// `particles` must have exactly CountParticles() elements
+func (s *System) Sync3(particles []Particle3) error {
+	if len(particles) != s.CountParticles() {
+		return fmt.Errorf("Sync3: got %v particles, system has %v", len(particles), s.CountParticles())
+	}
+	return s.renderState.GetParticles3(particles)
+}
+
+// returns a copy of current state of 3D particles in GPU memory
+func (s *System) Particles3() ([]Particle3, error) {
+	particles := make([]Particle3, s.CountParticles())
+	if err := s.Sync3(particles); err != nil {
+		return nil, err
+	}
+	return particles, nil
+}
+
+// loads compute technique, System reloads it when shader file or its includes change
 func NewComputeTechniqueFromFile(compShaderFile string) (*core.Technique, error) {
+	return loadTechnique(compShaderFile, func() (*core.Technique, []string, error) {
+		return newComputeTechniqueFromFile(compShaderFile)
+	})
+}
+
+func newComputeTechniqueFromFile(compShaderFile string) (*core.Technique, []string, error) {
 	log.Printf("Load compute technique: %v\n", compShaderFile)
 
-	text, err := ioutil.ReadFile(compShaderFile)
+	cs, err := preprocessShaderFile(compShaderFile)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	shaderSource := core.ComputeShaderSource(text)
+	shaderSource := core.ComputeShaderSource(cs.Text())
 
 	technique, err := core.NewComputeTechnique(&shaderSource)
 	if err != nil {
-		return nil, err
+		return nil, nil, cs.mapError(err)
+	}
+
+	if err = validateLayouts(technique); err != nil {
+		technique.Delete()
+		return nil, nil, fmt.Errorf("%v: %v", compShaderFile, err)
 	}
 
 	if err = LogTechniqueInfo(technique); err != nil {
-		return nil, err
+		technique.Delete()
+		return nil, nil, err
 	}
 
-	return technique, err
+	return technique, cs.files(), nil
 }
 
+// loads render technique, System reloads it when shader files or their includes change
 func NewRenderTechniqueFromFile(vertexShaderFile string, fragmentShaderFile string) (*core.Technique, error) {
+	return loadTechnique(vertexShaderFile+", "+fragmentShaderFile, func() (*core.Technique, []string, error) {
+		return newRenderTechniqueFromFile(vertexShaderFile, fragmentShaderFile)
+	})
+}
+
+func newRenderTechniqueFromFile(vertexShaderFile string, fragmentShaderFile string) (*core.Technique, []string, error) {
 	log.Printf("Load render technique: vs=%v fs=%v\n", vertexShaderFile, fragmentShaderFile)
 
-	vsText, err := ioutil.ReadFile(vertexShaderFile)
+	vs, err := preprocessShaderFile(vertexShaderFile)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	fsText, err := ioutil.ReadFile(fragmentShaderFile)
+	fs, err := preprocessShaderFile(fragmentShaderFile)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	vertexShaderSource := core.VertexShaderSource(vsText)
-	fragmentShaderSource := core.FragmentShaderSource(fsText)
+	vertexShaderSource := core.VertexShaderSource(vs.Text())
+	fragmentShaderSource := core.FragmentShaderSource(fs.Text())
 
 	technique, err := core.NewRenderTechnique(&vertexShaderSource, &fragmentShaderSource)
 	if err != nil {
-		return nil, err
+		return nil, nil, fs.mapError(vs.mapError(err))
+	}
+
+	if err = validateLayouts(technique); err != nil {
+		technique.Delete()
+		return nil, nil, fmt.Errorf("%v: %v", vertexShaderFile, err)
 	}
 
 	if err = LogTechniqueInfo(technique); err != nil {
-		return nil, err
+		technique.Delete()
+		return nil, nil, err
 	}
 
-	return technique, err
+	return technique, append(vs.files(), fs.files()...), nil
+}
+
+// checks that Particle matches std430 layout of particles in SSBO "Particles"
+// declared by technique, techniques without it are accepted as is
+func ValidateParticleLayout(t *core.Technique) error {
+	return inspect.ValidateBufferLayout(t, "Particles", "current_particles", reflect.TypeOf(Particle{}))
+}
+
+// checks layouts of particles and obstacles, particles are validated
+// against Particle or Particle3 depending on their stride
+func validateLayouts(t *core.Technique) error {
+	dimensions, err := particleDimensions(t)
+	if err != nil {
+		return err
+	}
+	if dimensions == 3 {
+		err = ValidateParticle3Layout(t)
+	} else {
+		err = ValidateParticleLayout(t)
+	}
+	if err != nil {
+		return err
+	}
+	return ValidateObstacleLayout(t)
 }
 
 func LogTechniqueInfo(t *core.Technique) error {
@@ -0,0 +1,150 @@
+package particles
+
+import "errors"
+import "os"
+import "runtime"
+import "testing"
+import "github.com/dmarychev/gazebo/core"
+
+// makes offscreen OpenGL context current for the rest of the test, GL calls must be
+// made from the test's goroutine, so subtests can't use it; skips test without context
+func withContext(t *testing.T) {
+	t.Helper()
+	for name, value := range core.MesaOverrides {
+		if os.Getenv(name) == "" {
+			os.Setenv(name, value)
+		}
+	}
+	runtime.LockOSThread()
+	hc, err := core.NewHeadlessContext(64, 64)
+	if err != nil {
+		runtime.UnlockOSThread()
+		t.Skipf("no headless OpenGL context: %v", err)
+	}
+	t.Cleanup(func() {
+		hc.Destroy()
+		runtime.UnlockOSThread()
+	})
+	if err := core.InitGL(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// dam break scene with particles of given emitters and fixed seed, without fluid rendering
+func loadTestScene(t *testing.T, emitters ...Emitter) *Scene {
+	t.Helper()
+	sc, err := LoadScene("../scenes/dam_break.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc.Seed = 1
+	sc.Render.Fluid = nil
+	sc.Emitters = emitters
+	return sc
+}
+
+// creates system of scene with its initial particles, closed at the end of the test
+func newTestSystem(t *testing.T, sc *Scene) (*System, []Particle) {
+	t.Helper()
+	s, err := sc.NewSystem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := s.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	particles, err := sc.Particles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetParticles(particles); err != nil {
+		t.Fatal(err)
+	}
+	return s, particles
+}
+
+// particles of the last partial workgroup are updated like the others
+func TestUpdateMovesTailParticles(t *testing.T) {
+	withContext(t)
+	sc := loadTestScene(t, Emitter{
+		Type:    "block",
+		Origin:  core.Vec3{X: -0.8, Y: -0.8},
+		Columns: 41,
+		Rows:    100,
+		Spacing: 0.01,
+		Jitter:  0.0005,
+		Mass:    0.01,
+	})
+	s, initial := newTestSystem(t, sc)
+	if len(initial) != 4100 || len(initial)%WORKGROUP_SIZE == 0 {
+		t.Fatalf("got %v particles, want 4100, which is not a multiple of WORKGROUP_SIZE", len(initial))
+	}
+
+	for step := 0; step < 5; step++ {
+		if err := s.Update(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	particles, err := s.Particles()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 4096; i < 4100; i++ {
+		if particles[i].R == initial[i].R {
+			t.Errorf("particle %v stays at %v", i, initial[i].R)
+		}
+		if particles[i].V == initial[i].V {
+			t.Errorf("velocity of particle %v stays %v", i, initial[i].V)
+		}
+	}
+}
+
+// walls without damping of their own follow damping of parameters
+func TestSetParametersUpdatesWallDamping(t *testing.T) {
+	withContext(t)
+	for _, backend := range []string{"gpu", "cpu"} {
+		sc := loadTestScene(t, oracleBlock)
+		sc.Backend = backend
+		own := float32(-0.5)
+		sc.Boundaries = &BoundariesDesc{Bottom: &WallDesc{Type: "reflect", Damping: &own}}
+		s, _ := newTestSystem(t, sc)
+
+		parameters := s.Parameters()
+		parameters.Damping = -0.25
+		if err := s.SetParameters(parameters); err != nil {
+			t.Fatal(err)
+		}
+		want := []float32{-0.25, -0.25, own, -0.25}
+
+		if backend == "cpu" {
+			for i, wall := range s.solver.Boundaries.Walls {
+				if wall.Damping != want[i] {
+					t.Errorf("cpu: damping of %v wall is %v, want %v", wallNames[i], wall.Damping, want[i])
+				}
+			}
+			continue
+		}
+		applied := 0
+		for _, technique := range s.renderState.techniques() {
+			damping := make([]float32, COUNT_WALLS)
+			if err := technique.GetUniformFloat32Array("wall_damping", damping); errors.Is(err, core.ErrUnknownUniform) {
+				continue
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			applied++
+			for i := range damping {
+				if damping[i] != want[i] {
+					t.Errorf("gpu: damping of %v wall is %v, want %v", wallNames[i], damping[i], want[i])
+				}
+			}
+		}
+		if applied == 0 {
+			t.Errorf("no technique declares wall_damping")
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import "fmt"
+import "image"
+import "image/color/palette"
+import "image/draw"
+import "image/gif"
+import "image/png"
+import "log"
+import "os"
+import "path/filepath"
+import "strings"
+import "github.com/dmarychev/gazebo/core"
+
+const (
+	GIF_FRAME_DELAY = 4 // delay between GIF frames in 100ths of second
+)
+
+// Records rendered frames to numbered PNG files in directory
+// or to animated GIF if output path ends with ".gif"
+type frameRecorder struct {
+	path      string   // output directory or GIF file
+	interval  int      // record every interval-th frame
+	maxFrames int      // number of frames to record, 0 means no limit
+	frame     int      // number of frames passed to Capture
+	recorded  int      // number of frames recorded
+	anim      *gif.GIF // frames of animated GIF, nil when recording PNGs
+}
+
+func newFrameRecorder(path string, interval, maxFrames int) (*frameRecorder, error) {
+	if interval < 1 {
+		return nil, fmt.Errorf("frame interval must be positive, got %v", interval)
+	}
+	fr := frameRecorder{path: path, interval: interval, maxFrames: maxFrames}
+	if strings.EqualFold(filepath.Ext(path), ".gif") {
+		fr.anim = &gif.GIF{}
+	} else if err := os.MkdirAll(path, 0755); err != nil {
+		return nil, err
+	}
+	return &fr, nil
+}
+
+// true if all requested frames are recorded
+func (fr *frameRecorder) Done() bool {
+	return fr.maxFrames > 0 && fr.recorded >= fr.maxFrames
+}
+
+// true if the next frame will be recorded
+func (fr *frameRecorder) Due() bool {
+	return !fr.Done() && fr.frame%fr.interval == 0
+}
+
+// counts frame which is not rendered
+func (fr *frameRecorder) Skip() {
+	fr.frame++
+}
+
+// records frame rendered into attachment of framebuffer, must be called before swapping buffers
+func (fr *frameRecorder) Capture(fbo core.FramebufferObject, attachment uint32, width, height int) error {
+	due := fr.Due()
+	fr.frame++
+	if !due {
+		return nil
+	}
+
+	img, err := fbo.ReadImage(attachment, int32(width), int32(height))
+	if err != nil {
+		return err
+	}
+	fr.recorded++
+
+	if fr.anim != nil {
+		paletted := image.NewPaletted(img.Bounds(), palette.Plan9)
+		draw.Draw(paletted, paletted.Rect, img, image.Point{}, draw.Src)
+		fr.anim.Image = append(fr.anim.Image, paletted)
+		fr.anim.Delay = append(fr.anim.Delay, GIF_FRAME_DELAY)
+		if fr.Done() {
+			return fr.Close()
+		}
+		return nil
+	}
+
+	f, err := os.Create(filepath.Join(fr.path, fmt.Sprintf("frame%05d.png", fr.recorded)))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return png.Encode(f, img)
+}
+
+// writes animated GIF, does nothing for PNG sequence or when GIF is already written
+func (fr *frameRecorder) Close() error {
+	if fr.anim == nil || len(fr.anim.Image) == 0 {
+		return nil
+	}
+
+	f, err := os.Create(fr.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := gif.EncodeAll(f, fr.anim); err != nil {
+		return err
+	}
+	log.Printf("Recorded %v frames to %v", len(fr.anim.Image), fr.path)
+
+	fr.anim.Image, fr.anim.Delay = nil, nil
+	return nil
+}
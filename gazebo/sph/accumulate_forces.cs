@@ -3,35 +3,21 @@
 
 layout(local_size_x = 16, local_size_y = 1, local_size_z = 1) in;
 
-struct Particle {
-    vec2 r;
-    vec2 v;
-    vec2 f;
-    vec2 prev_f;
-    float p; // pressure
-    float d; // density
-    float m; // mass
-    float _;
-};
-
-const float PI = 3.1415926535897932384626433832795;
+#include "particle.glsl"
+#include "index.glsl"
 
 uniform float g = 0.08; // gravity
 uniform float mu = 5.0; // viscosity coefficient
 uniform float h = 0.01; // smoothing parameter
-uniform uint index_max_neighbors = 40; // maximum number of neighbors in the index
-
-layout(std430, binding=0) buffer Particles {
-    Particle current_particles[];
-};
-
-layout(std430, binding=1) buffer Index {
-    uint index[];
-};
 
 void main()
 {
     uint p_i = gl_GlobalInvocationID.x;
+
+    if (p_i >= count_particles) {
+        return;
+    }
+
     Particle p = current_particles[p_i];
 
     uint index_base = p_i * index_max_neighbors;
@@ -44,7 +30,7 @@ void main()
     vec2 f_vis = vec2(.0f, .0f);
     for (uint i = 0; i < index_max_neighbors; i++) {
         uint neighbor_idx = index[index_base + i];
-        if (neighbor_idx == 0xdeadbeef) {
+        if (neighbor_idx == INDEX_EMPTY_SLOT) {
             break;
         }
         if (neighbor_idx != p_i) {
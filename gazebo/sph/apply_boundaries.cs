@@ -0,0 +1,62 @@
+// apply boundary conditions of domain walls, see particles.Boundaries
+#version 460
+#pragma optimize(off)
+
+layout(local_size_x = 16, local_size_y = 1, local_size_z = 1) in;
+
+#include "particle.glsl"
+
+// must match particles.WallBehavior
+const int WALL_REFLECT = 0;
+const int WALL_PERIODIC = 1;
+const int WALL_OPEN = 2;
+
+// walls are ordered as particles.WALL_LEFT, WALL_RIGHT, WALL_BOTTOM and WALL_TOP,
+// lower and upper walls of axis i are 2*i and 2*i+1
+uniform vec2 domain_min = vec2(-0.8, -0.8);
+uniform vec2 domain_max = vec2(0.8, 0.8);
+uniform int wall_behaviors[4] = int[](WALL_REFLECT, WALL_REFLECT, WALL_REFLECT, WALL_OPEN);
+uniform float wall_damping[4] = float[](-0.5, -0.5, -0.5, -0.5);
+
+const float eps = 0.001; // must match particles.BOUNDARY_EPS
+
+void apply_walls(inout Particle p, int axis)
+{
+    float lo = domain_min[axis];
+    float hi = domain_max[axis];
+
+    int wall;
+    float inside;
+    if (p.r[axis] <= lo) {
+        wall = 2 * axis;
+        inside = lo + eps;
+    } else if (p.r[axis] >= hi) {
+        wall = 2 * axis + 1;
+        inside = hi - eps;
+    } else {
+        return;
+    }
+
+    if (wall_behaviors[wall] == WALL_REFLECT) {
+        p.r[axis] = inside;
+        p.v *= wall_damping[wall];
+    } else if (wall_behaviors[wall] == WALL_PERIODIC) {
+        p.r[axis] = lo + mod(p.r[axis] - lo, hi - lo);
+    }
+}
+
+void main()
+{
+    uint gid = gl_GlobalInvocationID.x;
+
+    if (gid >= count_particles) {
+        return;
+    }
+
+    Particle p = current_particles[gid];
+
+    apply_walls(p, 0);
+    apply_walls(p, 1);
+
+    current_particles[gid] = p;
+}
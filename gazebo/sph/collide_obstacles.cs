@@ -0,0 +1,121 @@
+// collide particles with static obstacles, see particles.Obstacle
+#version 460
+//#pragma optimize(off)
+
+layout(local_size_x = 16, local_size_y = 1, local_size_z = 1) in;
+
+#include "particle.glsl"
+
+// must match particles.ObstacleType
+const uint OBSTACLE_CIRCLE = 0;
+const uint OBSTACLE_SEGMENT = 1;
+const uint OBSTACLE_POLYGON = 2;
+
+// must match particles.gpuObstacle
+struct Obstacle {
+    vec2 a; // center of circle or the first end of segment
+    vec2 b; // the second end of segment
+    float radius; // radius of circle or half of thickness of segment
+    float restitution;
+    float friction;
+    uint type;
+    uint first_vertex; // polygon's vertices in obstacle_vertices
+    uint count_vertices;
+};
+
+layout(std430, binding=6) buffer Obstacles {
+    Obstacle obstacles[];
+};
+
+layout(std430, binding=7) buffer ObstacleVertices {
+    vec2 obstacle_vertices[];
+};
+
+uniform uint count_obstacles = 0;
+
+const float eps = 0.001; // must match particles.BOUNDARY_EPS
+
+vec2 normal_or(vec2 d, vec2 fallback)
+{
+    float l = length(d);
+    return l > 0.0 ? d / l : fallback;
+}
+
+vec2 closest_on_segment(vec2 r, vec2 a, vec2 b)
+{
+    vec2 ab = b - a;
+    float l2 = dot(ab, ab);
+    float t = l2 > 0.0 ? clamp(dot(r - a, ab) / l2, 0.0, 1.0) : 0.0;
+    return a + t * ab;
+}
+
+// surface point of obstacle closest to r and outward normal at it,
+// returns true if r is inside of obstacle
+bool closest_surface(Obstacle o, vec2 r, out vec2 q, out vec2 n)
+{
+    if (o.type == OBSTACLE_CIRCLE) {
+        n = normal_or(r - o.a, vec2(0, 1));
+        q = o.a + n * o.radius;
+        return length(r - o.a) < o.radius;
+    }
+
+    if (o.type == OBSTACLE_SEGMENT) {
+        vec2 c = closest_on_segment(r, o.a, o.b);
+        n = normal_or(r - c, normal_or(vec2(o.a.y - o.b.y, o.b.x - o.a.x), vec2(0, 1)));
+        q = c + n * o.radius;
+        return length(r - c) < o.radius;
+    }
+
+    // polygon: the closest point of edges, inside by even-odd rule
+    float min_dist = 3.4e38;
+    bool inside = false;
+    for (uint i = 0; i < o.count_vertices; ++i) {
+        vec2 a = obstacle_vertices[o.first_vertex + i];
+        vec2 b = obstacle_vertices[o.first_vertex + (i + 1) % o.count_vertices];
+        vec2 c = closest_on_segment(r, a, b);
+        if (length(r - c) < min_dist) {
+            min_dist = length(r - c);
+            q = c;
+        }
+        if ((a.y > r.y) != (b.y > r.y) && r.x < a.x + (r.y - a.y) * (b.x - a.x) / (b.y - a.y)) {
+            inside = !inside;
+        }
+    }
+    n = inside ? normal_or(q - r, vec2(0, 1)) : normal_or(r - q, vec2(0, 1));
+    return inside;
+}
+
+void collide(Obstacle o, inout Particle p)
+{
+    vec2 q, n;
+    bool inside = closest_surface(o, p.r, q, n);
+    if (!inside && length(p.r - q) >= eps) {
+        return;
+    }
+
+    p.r = q + n * eps;
+
+    float vn = dot(p.v, n);
+    if (vn >= 0.0) {
+        return;
+    }
+    vec2 vt = p.v - vn * n;
+    p.v = vt * (1.0 - o.friction) - o.restitution * vn * n;
+}
+
+void main()
+{
+    uint gid = gl_GlobalInvocationID.x;
+
+    if (gid >= count_particles) {
+        return;
+    }
+
+    Particle p = current_particles[gid];
+
+    for (uint i = 0; i < count_obstacles; ++i) {
+        collide(obstacles[i], p);
+    }
+
+    current_particles[gid] = p;
+}
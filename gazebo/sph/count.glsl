@@ -0,0 +1,3 @@
+// number of particles, invocations beyond it must return, since
+// number of invocations is rounded up to multiple of workgroup size
+uniform uint count_particles;
@@ -0,0 +1,29 @@
+// uniform grid of neighbor search, cells are hashed to `grid_cells` buckets
+layout(std430, binding=2) buffer CellCount {
+    uint cell_count[];
+};
+
+layout(std430, binding=3) buffer CellStart {
+    uint cell_start[];
+};
+
+layout(std430, binding=4) buffer ParticleCell {
+    uvec2 particle_cell[]; // (cell, rank of particle in cell)
+};
+
+layout(std430, binding=5) buffer SortedParticles {
+    uint sorted_particles[];
+};
+
+uniform uint grid_cells = 65536; // number of hash table cells
+
+uint cell_hash(ivec2 c)
+{
+    return ((uint(c.x) * 73856093u) ^ (uint(c.y) * 19349663u)) % grid_cells;
+}
+
+// cell of 3D grid, see sph3/
+uint cell_hash(ivec3 c)
+{
+    return ((uint(c.x) * 73856093u) ^ (uint(c.y) * 19349663u) ^ (uint(c.z) * 83492791u)) % grid_cells;
+}
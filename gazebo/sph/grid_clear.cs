@@ -0,0 +1,11 @@
+// cleanup grid cells counters
+#version 460
+
+layout(local_size_x = 16, local_size_y = 1, local_size_z = 1) in;
+
+#include "grid.glsl"
+
+void main()
+{
+    cell_count[gl_GlobalInvocationID.x] = 0;
+}
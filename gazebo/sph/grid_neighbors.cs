@@ -0,0 +1,58 @@
+// update index by scanning neighboring grid cells
+#version 460
+
+layout(local_size_x = 16, local_size_y = 1, local_size_z = 1) in;
+
+#include "particle.glsl"
+#include "index.glsl"
+#include "grid.glsl"
+
+uniform float h = 0.01; // cell size
+
+void main()
+{
+    uint p_i = gl_GlobalInvocationID.x;
+
+    if (p_i >= count_particles) {
+        return;
+    }
+
+    Particle p = current_particles[p_i];
+
+    ivec2 c = ivec2(floor(p.r / h));
+    uint index_base = p_i * index_max_neighbors;
+    uint count = 0;
+
+    // different cells may share the same hash, scan every hash once
+    uint visited[9];
+    uint count_visited = 0;
+
+    for (int dy = -1; dy <= 1; dy++) {
+        for (int dx = -1; dx <= 1; dx++) {
+            uint cell = cell_hash(c + ivec2(dx, dy));
+
+            bool seen = false;
+            for (uint i = 0; i < count_visited; i++) {
+                seen = seen || visited[i] == cell;
+            }
+            if (seen) {
+                continue;
+            }
+            visited[count_visited++] = cell;
+
+            uint begin = cell_start[cell];
+            uint end = begin + cell_count[cell];
+            for (uint i = begin; i < end && count < index_max_neighbors; i++) {
+                uint candidate_i = sorted_particles[i];
+                vec2 d = p.r - current_particles[candidate_i].r;
+                if (length(d) < h) {
+                    index[index_base + count++] = candidate_i;
+                }
+            }
+        }
+    }
+
+    if (count < index_max_neighbors) {
+        index[index_base + count] = INDEX_EMPTY_SLOT;
+    }
+}
@@ -0,0 +1,45 @@
+// exclusive prefix sum of grid cells counters, dispatched as a single workgroup
+#version 460
+
+#define SCAN_SIZE 256
+
+layout(local_size_x = SCAN_SIZE, local_size_y = 1, local_size_z = 1) in;
+
+#include "grid.glsl"
+
+shared uint partial_sums[SCAN_SIZE];
+
+void main()
+{
+    uint t_i = gl_LocalInvocationID.x;
+
+    // every invocation scans its own contiguous chunk of cells
+    uint chunk = (grid_cells + SCAN_SIZE - 1) / SCAN_SIZE;
+    uint begin = min(t_i * chunk, grid_cells);
+    uint end = min(begin + chunk, grid_cells);
+
+    uint sum = 0;
+    for (uint i = begin; i < end; i++) {
+        sum += cell_count[i];
+    }
+    partial_sums[t_i] = sum;
+
+    memoryBarrierShared();
+    barrier();
+
+    // inclusive scan of chunks' sums
+    for (uint offset = 1; offset < SCAN_SIZE; offset <<= 1) {
+        uint v = t_i >= offset ? partial_sums[t_i - offset] : 0;
+        memoryBarrierShared();
+        barrier();
+        partial_sums[t_i] += v;
+        memoryBarrierShared();
+        barrier();
+    }
+
+    uint start = t_i > 0 ? partial_sums[t_i - 1] : 0;
+    for (uint i = begin; i < end; i++) {
+        cell_start[i] = start;
+        start += cell_count[i];
+    }
+}
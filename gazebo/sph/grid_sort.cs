@@ -0,0 +1,20 @@
+// counting sort of particles by grid cells
+#version 460
+
+layout(local_size_x = 16, local_size_y = 1, local_size_z = 1) in;
+
+#include "count.glsl"
+#include "grid.glsl"
+
+void main()
+{
+    uint p_i = gl_GlobalInvocationID.x;
+
+    if (p_i >= count_particles) {
+        return;
+    }
+
+    uvec2 pc = particle_cell[p_i];
+
+    sorted_particles[cell_start[pc.x] + pc.y] = p_i;
+}
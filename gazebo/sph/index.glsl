@@ -0,0 +1,8 @@
+// neighbors index, `index_max_neighbors` slots per particle, list is terminated by INDEX_EMPTY_SLOT
+const uint INDEX_EMPTY_SLOT = 0xdeadbeef;
+
+uniform uint index_max_neighbors = 40; // maximum number of neighbors in the index
+
+layout(std430, binding=1) buffer Index {
+    uint index[];
+};
@@ -3,16 +3,19 @@
 
 layout(local_size_x = 16, local_size_y = 1, local_size_z = 1) in;
 
-layout(std430, binding=1) buffer Index {
-    uint index[];
-};
-
-uniform uint index_max_neighbors = 40;
+#include "count.glsl"
+#include "index.glsl"
 
 void main()
 {
-    uint index_base = gl_GlobalInvocationID.x * index_max_neighbors;
+    uint p_i = gl_GlobalInvocationID.x;
+
+    if (p_i >= count_particles) {
+        return;
+    }
+
+    uint index_base = p_i * index_max_neighbors;
     for (uint i = 0; i < index_max_neighbors; i++) {
-        index[index_base + i] = 0xdeadbeef;
+        index[index_base + i] = INDEX_EMPTY_SLOT;
     }
 }
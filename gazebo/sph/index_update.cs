@@ -3,26 +3,9 @@
 
 layout(local_size_x = 16, local_size_y = 16, local_size_z = 1) in;
 
-struct Particle {
-    vec2 r;
-    vec2 v;
-    vec2 f;
-    vec2 prev_f;
-    float p; // pressure
-    float d; // density
-    float m; // mass
-    float _;
-};
+#include "particle.glsl"
+#include "index.glsl"
 
-layout(std430, binding=0) buffer Particles {
-    Particle current_particles[];
-};
-
-layout(std430, binding=1) buffer Index {
-    uint index[];
-};
-
-uniform uint index_max_neighbors = 40;
 uniform float h = 0.01;
 
 void main()
@@ -30,6 +13,10 @@ void main()
     uint p_i = gl_GlobalInvocationID.x;
     uint candidate_i = gl_GlobalInvocationID.y;
 
+    if (p_i >= count_particles || candidate_i >= count_particles) {
+        return;
+    }
+
     Particle p = current_particles[p_i];
     Particle candidate = current_particles[candidate_i];
 
@@ -37,7 +24,7 @@ void main()
     if (length(d) < h) {
         uint index_base = p_i * index_max_neighbors;
         for (uint i = 0; i < index_max_neighbors; i++) {
-            if (atomicCompSwap(index[index_base + i], 0xdeadbeef, candidate_i) == 0xdeadbeef) {
+            if (atomicCompSwap(index[index_base + i], INDEX_EMPTY_SLOT, candidate_i) == INDEX_EMPTY_SLOT) {
                 break;
             }
         }
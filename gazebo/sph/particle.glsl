@@ -0,0 +1,19 @@
+// particle layout shared by all stages, must match particles.Particle
+struct Particle {
+    vec2 r;
+    vec2 v;
+    vec2 f;
+    vec2 prev_f;
+    float p; // pressure
+    float d; // density
+    float m; // mass
+    float _;
+};
+
+#include "count.glsl"
+
+const float PI = 3.1415926535897932384626433832795;
+
+layout(std430, binding=0) buffer Particles {
+    Particle current_particles[];
+};
@@ -0,0 +1,57 @@
+// calculate forces of 3D particles, gravity is directed along -y
+#version 460
+
+layout(local_size_x = 16, local_size_y = 1, local_size_z = 1) in;
+
+#include "particle.glsl"
+#include "../sph/index.glsl"
+
+uniform float g = 0.08; // gravity
+uniform float mu = 5.0; // viscosity coefficient
+uniform float h = 0.01; // smoothing parameter
+
+void main()
+{
+    uint p_i = gl_GlobalInvocationID.x;
+
+    if (p_i >= count_particles) {
+        return;
+    }
+
+    Particle p = current_particles[p_i];
+
+    uint index_base = p_i * index_max_neighbors;
+
+    // gradient of spiky and laplacian of viscosity kernels normalized in 3D
+    const float k_h6 = h * h * h * h * h * h;
+    const float k_grad_coeff = -45.0f / (PI * k_h6);
+    const float k_lap_coeff = 45.f / (PI * k_h6);
+
+    vec3 f_press = vec3(0);
+    vec3 f_vis = vec3(0);
+    for (uint i = 0; i < index_max_neighbors; i++) {
+        uint neighbor_idx = index[index_base + i];
+        if (neighbor_idx == INDEX_EMPTY_SLOT) {
+            break;
+        }
+        if (neighbor_idx != p_i) {
+            Particle o = current_particles[neighbor_idx];
+
+            vec3 dr = p.r - o.r;
+            float ldr = length(dr);
+            vec3 ndr = ldr > 0 ? dr / ldr : vec3(0);
+
+            // pressure force
+            f_press += -(o.m / o.d) * 0.5 * (o.p + p.p) * k_grad_coeff * (h - ldr) * (h - ldr) * ndr;
+
+            // viscosity force
+            f_vis += (o.m / o.d) * (o.v - p.v) * k_lap_coeff * (h - ldr);
+        }
+    }
+
+    vec3 f_grav = vec3(0, -p.d * g, 0);
+
+    p.f = f_press + f_grav + mu * f_vis;
+
+    current_particles[p_i] = p;
+}
@@ -0,0 +1,62 @@
+// apply boundary conditions of walls of 3D domain, see particles.Boundaries3
+#version 460
+
+layout(local_size_x = 16, local_size_y = 1, local_size_z = 1) in;
+
+#include "particle.glsl"
+
+// must match particles.WallBehavior
+const int WALL_REFLECT = 0;
+const int WALL_PERIODIC = 1;
+const int WALL_OPEN = 2;
+
+// walls are ordered as particles.WALL_LEFT, WALL_RIGHT, WALL_BOTTOM, WALL_TOP,
+// WALL_BACK and WALL_FRONT, lower and upper walls of axis i are 2*i and 2*i+1
+uniform vec3 domain_min = vec3(-0.8, -0.8, -0.8);
+uniform vec3 domain_max = vec3(0.8, 0.8, 0.8);
+uniform int wall_behaviors[6] = int[](WALL_REFLECT, WALL_REFLECT, WALL_REFLECT, WALL_OPEN, WALL_REFLECT, WALL_REFLECT);
+uniform float wall_damping[6] = float[](-0.5, -0.5, -0.5, -0.5, -0.5, -0.5);
+
+const float eps = 0.001; // must match particles.BOUNDARY_EPS
+
+void apply_walls(inout Particle p, int axis)
+{
+    float lo = domain_min[axis];
+    float hi = domain_max[axis];
+
+    int wall;
+    float inside;
+    if (p.r[axis] <= lo) {
+        wall = 2 * axis;
+        inside = lo + eps;
+    } else if (p.r[axis] >= hi) {
+        wall = 2 * axis + 1;
+        inside = hi - eps;
+    } else {
+        return;
+    }
+
+    if (wall_behaviors[wall] == WALL_REFLECT) {
+        p.r[axis] = inside;
+        p.v *= wall_damping[wall];
+    } else if (wall_behaviors[wall] == WALL_PERIODIC) {
+        p.r[axis] = lo + mod(p.r[axis] - lo, hi - lo);
+    }
+}
+
+void main()
+{
+    uint gid = gl_GlobalInvocationID.x;
+
+    if (gid >= count_particles) {
+        return;
+    }
+
+    Particle p = current_particles[gid];
+
+    apply_walls(p, 0);
+    apply_walls(p, 1);
+    apply_walls(p, 2);
+
+    current_particles[gid] = p;
+}
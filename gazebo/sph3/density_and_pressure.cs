@@ -0,0 +1,45 @@
+// calculate density and pressure of 3D particles
+#version 460
+
+layout(local_size_x = 16, local_size_y = 1, local_size_z = 1) in;
+
+#include "particle.glsl"
+#include "../sph/index.glsl"
+
+uniform float h = 0.01;
+uniform float k = 0.01;
+
+void main()
+{
+    uint p_i = gl_GlobalInvocationID.x;
+
+    if (p_i >= count_particles) {
+        return;
+    }
+
+    Particle p = current_particles[p_i];
+
+    uint index_base = p_i * index_max_neighbors;
+
+    // poly6 kernel normalized in 3D
+    const float k_poly6_coeff = 315.0/(64.0 * PI * pow(h, 9));
+    const float h2 = h * h;
+
+    p.d = 0.0;
+    for (uint i = 0; i < index_max_neighbors; i++) {
+        uint neighbor_idx = index[index_base + i];
+        if (neighbor_idx == INDEX_EMPTY_SLOT) {
+            break;
+        }
+        Particle o = current_particles[neighbor_idx];
+
+        vec3 dr = p.r - o.r;
+        float dr2 = dot(dr, dr);
+        float d_h2_dr2 = h2 - dr2;
+
+        p.d += o.m * k_poly6_coeff * d_h2_dr2 * d_h2_dr2 * d_h2_dr2;
+    }
+
+    p.p = k * p.d;
+    current_particles[p_i] = p;
+}
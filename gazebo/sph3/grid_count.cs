@@ -0,0 +1,25 @@
+// count 3D particles in grid cells
+#version 460
+
+layout(local_size_x = 16, local_size_y = 1, local_size_z = 1) in;
+
+#include "particle.glsl"
+#include "../sph/grid.glsl"
+
+uniform float h = 0.01; // cell size
+
+void main()
+{
+    uint p_i = gl_GlobalInvocationID.x;
+
+    if (p_i >= count_particles) {
+        return;
+    }
+
+    Particle p = current_particles[p_i];
+
+    uint cell = cell_hash(ivec3(floor(p.r / h)));
+    uint rank = atomicAdd(cell_count[cell], 1);
+
+    particle_cell[p_i] = uvec2(cell, rank);
+}
@@ -0,0 +1,32 @@
+// update index of 3D particles
+#version 460
+
+layout(local_size_x = 16, local_size_y = 16, local_size_z = 1) in;
+
+#include "particle.glsl"
+#include "../sph/index.glsl"
+
+uniform float h = 0.01;
+
+void main()
+{
+    uint p_i = gl_GlobalInvocationID.x;
+    uint candidate_i = gl_GlobalInvocationID.y;
+
+    if (p_i >= count_particles || candidate_i >= count_particles) {
+        return;
+    }
+
+    Particle p = current_particles[p_i];
+    Particle candidate = current_particles[candidate_i];
+
+    vec3 d = p.r - candidate.r;
+    if (length(d) < h) {
+        uint index_base = p_i * index_max_neighbors;
+        for (uint i = 0; i < index_max_neighbors; i++) {
+            if (atomicCompSwap(index[index_base + i], INDEX_EMPTY_SLOT, candidate_i) == INDEX_EMPTY_SLOT) {
+                break;
+            }
+        }
+    }
+}
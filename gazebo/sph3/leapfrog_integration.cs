@@ -0,0 +1,26 @@
+// leapfrog integration of movement equations of 3D particles
+#version 460
+
+layout(local_size_x = 16, local_size_y = 1, local_size_z = 1) in;
+
+#include "particle.glsl"
+
+uniform float dt = 0.01;
+
+void main()
+{
+    uint gid = gl_GlobalInvocationID.x;
+
+    if (gid >= count_particles) {
+        return;
+    }
+
+    Particle p = current_particles[gid];
+
+    vec3 v_half = p.v + 0.5 * dt * p.prev_f / p.d;
+    p.r = p.r + v_half * dt;
+    p.v = v_half + 0.5 * dt * p.f / p.d;
+    p.prev_f = p.f; // save for the next step
+
+    current_particles[gid] = p;
+}
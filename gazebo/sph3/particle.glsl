@@ -0,0 +1,20 @@
+// 3D particle layout shared by all stages of sph3, must match particles.Particle3,
+// scalars fill padding of vec3 members
+struct Particle {
+    vec3 r;
+    float p; // pressure
+    vec3 v;
+    float d; // density
+    vec3 f;
+    float m; // mass
+    vec3 prev_f;
+    float _;
+};
+
+#include "../sph/count.glsl"
+
+const float PI = 3.1415926535897932384626433832795;
+
+layout(std430, binding=0) buffer Particles {
+    Particle current_particles[];
+};
@@ -0,0 +1,26 @@
+// must match ColorField in particles/colormap.go
+const int COLOR_FIELD_NONE = 0;
+const int COLOR_FIELD_SPEED = 1;
+const int COLOR_FIELD_DENSITY = 2;
+const int COLOR_FIELD_PRESSURE = 3;
+const int COLOR_FIELD_FORCE = 4;
+const int COLOR_FIELD_MASS = 5;
+
+uniform int color_field = COLOR_FIELD_NONE; // scalar field mapped to color
+uniform vec2 value_range = vec2(0.0, 1.0); // values mapped to the ends of colormap
+
+// value of color field normalized to [0, 1], negative for constant color
+float color_value(float speed, float force, float pressure, float density, float mass)
+{
+    float v;
+    switch (color_field) {
+    case COLOR_FIELD_SPEED: v = speed; break;
+    case COLOR_FIELD_DENSITY: v = density; break;
+    case COLOR_FIELD_PRESSURE: v = pressure; break;
+    case COLOR_FIELD_FORCE: v = force; break;
+    case COLOR_FIELD_MASS: v = mass; break;
+    default:
+        return -1.0;
+    }
+    return clamp((v - value_range.x) / (value_range.y - value_range.x), 0.0, 1.0);
+}
@@ -0,0 +1,42 @@
+// colormaps as piecewise linear interpolation of evenly spaced control points
+
+// must match Colormap in particles/colormap.go
+const int COLORMAP_VIRIDIS = 0;
+const int COLORMAP_COOLWARM = 1;
+
+const int COLORMAP_POINTS = 9;
+
+const vec3 VIRIDIS[COLORMAP_POINTS] = vec3[](
+    vec3(0.267004, 0.004874, 0.329415),
+    vec3(0.278826, 0.175490, 0.483397),
+    vec3(0.229739, 0.322361, 0.545706),
+    vec3(0.172719, 0.448791, 0.557885),
+    vec3(0.127568, 0.566949, 0.550556),
+    vec3(0.157851, 0.683765, 0.501686),
+    vec3(0.369214, 0.788888, 0.382914),
+    vec3(0.678489, 0.863742, 0.189503),
+    vec3(0.993248, 0.906157, 0.143936)
+);
+
+const vec3 COOLWARM[COLORMAP_POINTS] = vec3[](
+    vec3(0.229806, 0.298718, 0.753683),
+    vec3(0.353299, 0.472955, 0.893803),
+    vec3(0.486000, 0.629000, 0.975000),
+    vec3(0.619608, 0.754902, 0.998039),
+    vec3(0.865003, 0.865003, 0.865003),
+    vec3(0.962746, 0.720485, 0.614796),
+    vec3(0.958852, 0.540679, 0.426398),
+    vec3(0.884171, 0.340686, 0.265096),
+    vec3(0.705673, 0.015556, 0.150233)
+);
+
+// color of value in [0, 1]
+vec3 apply_colormap(int colormap, float value) {
+    float x = clamp(value, 0.0, 1.0) * float(COLORMAP_POINTS - 1);
+    int i = min(int(floor(x)), COLORMAP_POINTS - 2);
+    float t = x - float(i);
+    if (colormap == COLORMAP_COOLWARM) {
+        return mix(COOLWARM[i], COOLWARM[i + 1], t);
+    }
+    return mix(VIRIDIS[i], VIRIDIS[i + 1], t);
+}
@@ -0,0 +1,38 @@
+#version 460
+
+// separable bilateral blur of fluid depth, smooths particles' spheres into continuous
+// surface without blurring over silhouettes, zero depth means no fluid
+
+uniform sampler2D depth_texture;
+uniform ivec2 direction = ivec2(1, 0); // blur direction in texels
+uniform int blur_radius = 16; // radius of filter in texels
+uniform float depth_falloff = 0.01; // difference of depths halving the weight
+
+out float depth;
+
+void main() {
+    ivec2 p = ivec2(gl_FragCoord.xy);
+    ivec2 size = textureSize(depth_texture, 0);
+
+    float center = texelFetch(depth_texture, p, 0).r;
+    if (center <= 0.0) {
+        depth = 0.0;
+        return;
+    }
+
+    float sigma = max(float(blur_radius) / 2.0, 1.0);
+    float sum = 0.0;
+    float weights = 0.0;
+    for (int i = -blur_radius; i <= blur_radius; i++) {
+        ivec2 q = clamp(p + i * direction, ivec2(0), size - 1);
+        float d = texelFetch(depth_texture, q, 0).r;
+        if (d <= 0.0) {
+            continue;
+        }
+        float dd = (d - center) / depth_falloff;
+        float w = exp(-float(i * i) / (2.0 * sigma * sigma)) * exp(-dd * dd);
+        sum += d * w;
+        weights += w;
+    }
+    depth = sum / weights;
+}
@@ -0,0 +1,58 @@
+#version 460
+
+// shades fluid surface reconstructed from blurred depth, normals are found
+// by finite differences of depth, color is absorbed along thickness of fluid
+
+in vec2 tex_coord;
+
+uniform sampler2D depth_texture;
+uniform sampler2D thickness_texture;
+uniform vec3 fluid_color = vec3(0.1, 0.4, 0.9); // color of deep fluid
+uniform float absorption = 40.0; // absorption coefficient per unit of thickness
+uniform vec2 texel_size = vec2(2.0 / 1920.0, 2.0 / 1080.0); // size of texel in units of coordinates
+
+out vec4 frag_color;
+
+const vec3 light_dir = normalize(vec3(-0.4, 0.6, 1.0));
+
+float depth_at(ivec2 p) {
+    return texelFetch(depth_texture, clamp(p, ivec2(0), textureSize(depth_texture, 0) - 1), 0).r;
+}
+
+// one-sided difference at silhouette, where neighbor has no fluid
+float derivative(float center, float prev, float next, float h) {
+    if (prev <= 0.0 && next <= 0.0) {
+        return 0.0;
+    } else if (prev <= 0.0) {
+        return (next - center) / h;
+    } else if (next <= 0.0) {
+        return (center - prev) / h;
+    }
+    return (next - prev) / (2.0 * h);
+}
+
+void main() {
+    ivec2 p = ivec2(gl_FragCoord.xy);
+    float d = depth_at(p);
+    if (d <= 0.0) {
+        discard;
+    }
+
+    float dx = derivative(d, depth_at(p - ivec2(1, 0)), depth_at(p + ivec2(1, 0)), texel_size.x);
+    float dy = derivative(d, depth_at(p - ivec2(0, 1)), depth_at(p + ivec2(0, 1)), texel_size.y);
+    vec3 n = normalize(vec3(-dx, -dy, 1.0));
+
+    float thickness = 2.0 * texelFetch(thickness_texture, p, 0).r;
+    vec3 transmitted = exp(-absorption * thickness * (1.0 - fluid_color));
+
+    vec3 view = vec3(0, 0, 1);
+    float diffuse = max(dot(n, light_dir), 0.0);
+    float specular = pow(max(dot(n, normalize(light_dir + view)), 0.0), 64.0);
+    float fresnel = 0.02 + 0.98 * pow(1.0 - max(dot(n, view), 0.0), 5.0);
+
+    vec3 color = mix(fluid_color, transmitted, 0.5) * (0.35 + 0.65 * diffuse);
+    color = mix(color, vec3(0.9, 0.95, 1.0), fresnel) + vec3(specular);
+
+    float alpha = clamp(1.0 - exp(-absorption * thickness), 0.6, 1.0);
+    frag_color = vec4(color, alpha);
+}
@@ -0,0 +1,20 @@
+#version 460
+
+// height of particle's sphere above the plane of simulation, used as depth of fluid surface
+// with max blending and as thickness of fluid with additive blending
+
+uniform float particle_radius = 0.01;
+
+flat in vec2 pixel_scale; // pixels per unit of coordinates along x and y
+
+out float height;
+
+void main() {
+    // point is square of the larger scale, particle is ellipse when scales differ
+    vec2 c = (2.0 * gl_PointCoord - 1.0) * max(pixel_scale.x, pixel_scale.y) / pixel_scale;
+    float r2 = dot(c, c);
+    if (r2 > 1.0) {
+        discard;
+    }
+    height = particle_radius * sqrt(1.0 - r2);
+}
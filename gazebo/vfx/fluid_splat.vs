@@ -0,0 +1,17 @@
+#version 460
+
+// particles as point sprites of `particle_radius` for fluid surface rendering
+
+layout(location = 0) in vec2 p_location; // must match ATTRIB_COORDINATES in particles/render.go
+
+uniform mat4 projection = mat4(1.0); // world to clip space transformation of orthographic camera
+uniform float particle_radius = 0.01; // radius of particle's sphere
+uniform vec2 point_scale = vec2(960.0, 540.0); // pixels per unit of normalized device coordinates, half of viewport's size
+
+flat out vec2 pixel_scale; // pixels per unit of coordinates along x and y
+
+void main() {
+    gl_Position = projection * vec4(p_location.xy, 0, 1);
+    pixel_scale = vec2(projection[0][0], projection[1][1]) * point_scale;
+    gl_PointSize = 2.0 * particle_radius * max(pixel_scale.x, pixel_scale.y);
+}
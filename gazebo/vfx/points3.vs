@@ -0,0 +1,29 @@
+#version 460
+
+// 3D particles as points in perspective of particles.Camera
+
+// locations must match ATTRIB_* in particles/render.go
+layout(location = 0) in vec3 p_location;
+layout(location = 1) in vec3 p_velocity;
+layout(location = 2) in vec3 p_force;
+layout(location = 3) in float p_pressure;
+layout(location = 4) in float p_density;
+layout(location = 5) in float p_mass;
+
+#include "color_field.glsl"
+
+uniform mat4 view;
+uniform mat4 projection;
+uniform float point_scale = 540.0; // pixels per unit of normalized device coordinates vertically
+uniform float particle_radius = 0.01;
+
+out float value; // field value normalized to [0, 1], negative for constant color
+
+void main() {
+    gl_Position = projection * view * vec4(p_location, 1);
+
+    // diameter in pixels shrinks with distance
+    gl_PointSize = max(2.0, 2.0 * particle_radius * projection[1][1] * point_scale / gl_Position.w);
+
+    value = color_value(length(p_velocity), length(p_force), p_pressure, p_density, p_mass);
+}
@@ -0,0 +1,11 @@
+#version 460
+
+// fullscreen triangle, drawn with 3 vertices without attributes
+
+out vec2 tex_coord;
+
+void main() {
+    vec2 p = vec2((gl_VertexID << 1) & 2, gl_VertexID & 2);
+    tex_coord = p;
+    gl_Position = vec4(2.0 * p - 1.0, 0, 1);
+}
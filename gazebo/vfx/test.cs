@@ -4,27 +4,18 @@
 
 layout(local_size_x = 1, local_size_y = 1, local_size_z = 1) in;
 
-struct Particle {
-    vec2 r;
-    vec2 v;
-    vec2 f_vis;
-    vec2 f_press;
-    vec2 f_grav;
-    vec2 f_total;
-    float p; // pressure
-    float d; // density
-    float m; // mass
-};
-
-layout(binding=0) buffer Particles {
-    Particle current_particles[];
-};
+#include "../sph/particle.glsl"
 
 void main()
 {
     vec2 accel = vec2(0, -0.01);
 
     uint gid = gl_GlobalInvocationID.x;
+
+    if (gid >= count_particles) {
+        return;
+    }
+
     float dt = 0.01;
 
     Particle p = current_particles[gid];
@@ -2,8 +2,18 @@
 
 //#pragma optimize(off)
 
+#include "colormap.glsl"
+
+uniform int colormap = COLORMAP_VIRIDIS;
+
+in float value;
+
 out vec4 frag_color;
 
 void main() {
-    frag_color = vec4(0, 0, 1, 1.0);
+    if (value < 0.0) {
+        frag_color = vec4(0, 0, 1, 1.0);
+    } else {
+        frag_color = vec4(apply_colormap(colormap, value), 1.0);
+    }
 }
@@ -2,9 +2,23 @@
 
 //#pragma optimize(off)
 
-in vec2 p_location;
+// locations must match ATTRIB_* in particles/render.go
+layout(location = 0) in vec2 p_location;
+layout(location = 1) in vec2 p_velocity;
+layout(location = 2) in vec2 p_force;
+layout(location = 3) in float p_pressure;
+layout(location = 4) in float p_density;
+layout(location = 5) in float p_mass;
+
+#include "color_field.glsl"
+
+uniform mat4 projection = mat4(1.0); // world to clip space transformation of orthographic camera
+
+out float value; // field value normalized to [0, 1], negative for constant color
 
 void main() {
-    gl_Position = vec4(p_location.xy, 0, 1);
+    gl_Position = projection * vec4(p_location.xy, 0, 1);
     gl_PointSize = 5.0;
+
+    value = color_value(length(p_velocity), length(p_force), p_pressure, p_density, p_mass);
 }